@@ -4,10 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	svn "github.com/kfsone/svn-go/lib"
 )
 
-// -dump: required, specifies name of the dump file to read.
-var dumpFileName = flag.String("read", "svn.dump", "path or glob of dump file(s) to be read")
+// -dump: required, specifies name of the dump file to read, or '-' for stdin.
+var dumpFileName = flag.String("read", "svn.dump", "path or glob of dump file(s) to be read, or - for stdin (held in memory in full)")
 
 // -rules: optional, specifies a rules file to work with. default: rules.yml
 var rulesFile = flag.String("rules", "", "optional path to rules file")
@@ -18,18 +23,74 @@ var verbose = flag.Bool("verbose", false, "emit more output")
 // -quiet: suppress verbose output.
 var quiet = flag.Bool("quiet", false, "suppress more output")
 
-// -outfile: optional, write the entire dump to one file
-var outFilename = flag.String("outfile", "", "specify a single file/path to write the entire dump to")
+// -outfile: optional, write the entire dump to one file, or '-' for stdout.
+var outFilename = flag.String("outfile", "", "specify a single file/path to write the entire dump to, or - for stdout")
 
 // -outdir: generate dump files in this directory.
 var outDir = flag.String("outdir", "", "specify a directory to write dump file(s) to")
 
+// -deltas: write format 3 dumps with node text delta-encoded against its predecessor.
+var writeDeltas = flag.Bool("deltas", false, "write delta-encoded (format 3) dumps, like svnadmin dump --deltas")
+
 // -remove-originals: remove the original dump files once they are regenerated. requires -outdir
 var removeOriginals = flag.Bool("remove-originals", false, "remove original dump files once they are regenerated. requires -outdir")
 
+// -verify-checksums: check node text against the md5/sha1 recorded in the dump.
+var verifyChecksums = flag.Bool("verify-checksums", false, "verify node text against Text-content-md5/sha1 headers while loading")
+
+// -verify-roundtrip: check that re-encoding the loaded dumps reproduces them exactly.
+var verifyRoundtrip = flag.Bool("verify-roundtrip", false, "re-encode the loaded dumps without applying rules and compare with the originals")
+
+// -repair-crlf: undo Windows line-ending translation of the dump files.
+var repairCRLF = flag.Bool("repair-crlf", false, "write copies of the dump files with Windows line-ending translation reversed, to -outfile or -outdir")
+
+// -log: show the history of a path.
+var logPath = flag.String("log", "", "show the revisions that touched `path`, following copies, after applying any rules; -revs limits the range")
+
+// -diff: show what changed between two revisions. Like -log and the other
+// reports, it is written to the console: stdout, or stderr when stdout is
+// carrying a dump or git stream.
+var diffRange = flag.String("diff", "", "show a unified diff between revisions `A:B`, or of the change made by revision N, after applying any rules")
+
+// -path: the subtree commands such as -diff apply to.
+var subtreePath = flag.String("path", "", "limit -diff and -export to the subtree at `path`")
+
+// -export: write out the files as they were at a revision.
+var exportRevision = flag.String("export", "", "write the -path subtree as it was at `revision` to the -to directory, after applying any rules")
+
+// -to: where -export writes to.
+var exportDir = flag.String("to", "", "empty or new `directory` for -export to write to")
+
+// -eol-style: have -export translate newlines.
+var exportEOLStyle = flag.Bool("eol-style", false, "have -export translate newlines in files with svn:eol-style")
+
+// -git: write the history as a git fast-import stream.
+var gitFilename = flag.String("git", "", "write the history, after applying any rules, as a `git fast-import` stream to a file, or - for stdout; -path sets where the trunk/branches/tags layout is")
+
+// -git-state: resume a git conversion, and record where it got to.
+var gitStateFilename = flag.String("git-state", "", "`file` recording the revisions and marks -git has already converted, so later runs only write new commits")
+
+// -require-authors: fail if the authors rules miss anyone.
+var requireAuthors = flag.Bool("require-authors", false, "fail if any svn:author isn't mapped by the 'authors' rules")
+
+// -jobs: how many dump files to parse at once.
+var jobs = flag.Int("jobs", runtime.NumCPU(), "number of dump files to parse concurrently")
+
 // -pathinfo: displays a list of all the paths that are created (and when) in the dump.
 var pathInfo = flag.Bool("pathinfo", false, "display paths created in the loaded dump")
 
+// -index: use and maintain a revision index beside each dump file.
+var useIndex = flag.Bool("index", false, "use and maintain a revision index ("+svn.IndexSuffix+") beside each dump file")
+
+// -revs: only work with a range of revisions.
+var revisionRange = flag.String("revs", "", "only load revisions `first:last` (or a single revision), without applying rules")
+
+// Bounds parsed from -revs.
+var rangeFirst, rangeLast int
+
+// Revision parsed from -export.
+var exportRev int
+
 func parseCommandLine() {
 	// Process command line flags.
 	flag.Parse()
@@ -52,13 +113,129 @@ func parseCommandLine() {
 		os.Exit(1)
 	}
 
+	if *dumpFileName == stdStream && (*outDir != "" || *removeOriginals) {
+		fmt.Println("-read - can only be used with -outfile")
+		os.Exit(1)
+	}
+
 	if *removeOriginals && *outDir == "" {
 		fmt.Println("-remove-originals requires -outdir")
 		os.Exit(1)
 	}
 
+	if *revisionRange != "" {
+		if err := parseRevisionRange(*revisionRange); err != nil {
+			fmt.Printf("invalid -revs: %s\n", err)
+			os.Exit(1)
+		}
+		if *logPath == "" && (*rulesFile != "" || *writeDeltas || *removeOriginals) {
+			fmt.Println("-revs cannot be combined with -rules, -deltas or -remove-originals")
+			os.Exit(1)
+		}
+		if *logPath != "" && (*outFilename != "" || *outDir != "") {
+			fmt.Println("-revs with -log limits the log, and cannot be combined with -outfile or -outdir")
+			os.Exit(1)
+		}
+	}
+
+	if *verifyRoundtrip {
+		if *dumpFileName == stdStream || *revisionRange != "" {
+			fmt.Println("-verify-roundtrip needs dump files, and cannot be combined with -revs")
+			os.Exit(1)
+		}
+		if *rulesFile != "" || *outFilename != "" || *outDir != "" {
+			fmt.Println("-verify-roundtrip cannot be combined with -rules, -outfile or -outdir")
+			os.Exit(1)
+		}
+	}
+
+	if *repairCRLF {
+		if *outFilename == "" && *outDir == "" {
+			fmt.Println("-repair-crlf requires -outfile or -outdir")
+			os.Exit(1)
+		}
+		if *rulesFile != "" || *revisionRange != "" || *writeDeltas || *verifyRoundtrip || *removeOriginals {
+			fmt.Println("-repair-crlf cannot be combined with -rules, -revs, -deltas, -verify-roundtrip or -remove-originals")
+			os.Exit(1)
+		}
+	}
+
+	if *diffRange != "" {
+		if err := parseDiffRange(*diffRange); err != nil {
+			fmt.Printf("invalid -diff: %s\n", err)
+			os.Exit(1)
+		}
+		if *revisionRange != "" && *logPath == "" {
+			fmt.Println("-diff has its own revisions, so can't be combined with -revs")
+			os.Exit(1)
+		}
+	}
+
+	if *exportRevision != "" {
+		var err error
+		if exportRev, err = strconv.Atoi(*exportRevision); err != nil || exportRev < 0 {
+			fmt.Printf("invalid -export: %s\n", *exportRevision)
+			os.Exit(1)
+		}
+		if *exportDir == "" {
+			fmt.Println("-export requires -to")
+			os.Exit(1)
+		}
+		if *revisionRange != "" && *logPath == "" {
+			fmt.Println("-export has its own revision, so can't be combined with -revs")
+			os.Exit(1)
+		}
+	} else if *exportDir != "" || *exportEOLStyle {
+		fmt.Println("-to and -eol-style require -export")
+		os.Exit(1)
+	}
+
+	if *gitStateFilename != "" && *gitFilename == "" {
+		fmt.Println("-git-state requires -git")
+		os.Exit(1)
+	}
+
+	if *gitFilename != "" {
+		if *revisionRange != "" && *logPath == "" {
+			fmt.Println("-git needs the whole history, so can't be combined with -revs")
+			os.Exit(1)
+		}
+		if *gitFilename == stdStream && *outFilename == stdStream {
+			fmt.Println("-git - can't share stdout with -outfile -")
+			os.Exit(1)
+		}
+	}
+
+	if *jobs < 1 {
+		fmt.Println("-jobs must be at least 1")
+		os.Exit(1)
+	}
+
 	if *verbose && *quiet {
 		fmt.Println("-quiet and -verbose are mutually exclusive")
 		os.Exit(1)
 	}
+
+	// Keep stdout clean when the dump itself is being written there.
+	if *outFilename == stdStream || *gitFilename == stdStream {
+		console = os.Stderr
+	}
+}
+
+// parseRevisionRange interprets "first:last" or a single revision number.
+func parseRevisionRange(text string) (err error) {
+	first, last, isRange := strings.Cut(text, ":")
+	if rangeFirst, err = strconv.Atoi(first); err != nil {
+		return err
+	}
+	rangeLast = rangeFirst
+	if isRange {
+		if rangeLast, err = strconv.Atoi(last); err != nil {
+			return err
+		}
+	}
+	if rangeFirst < 0 || rangeLast < rangeFirst {
+		return fmt.Errorf("%s is not an ascending range", text)
+	}
+	return nil
 }
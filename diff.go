@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Revisions parsed from -diff.
+var diffFrom, diffTo int
+
+// parseDiffRange interprets "A:B", or "N" as the change made by rN.
+func parseDiffRange(text string) (err error) {
+	from, to, isRange := strings.Cut(text, ":")
+	if !isRange {
+		if diffTo, err = strconv.Atoi(text); err != nil {
+			return err
+		}
+		diffFrom = diffTo - 1
+	} else {
+		if diffFrom, err = strconv.Atoi(from); err != nil {
+			return err
+		}
+		if diffTo, err = strconv.Atoi(to); err != nil {
+			return err
+		}
+	}
+	if diffFrom < 0 || diffTo < 0 {
+		return fmt.Errorf("%s includes a negative revision", text)
+	}
+	return nil
+}
+
+// showDiff prints a unified diff of the -path subtree between the -diff
+// revisions, as they are after applying any rules, to the console.
+func showDiff(status *Status) error {
+	return status.Diff(console, *subtreePath, diffFrom, diffTo)
+}
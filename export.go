@@ -0,0 +1,17 @@
+package main
+
+import (
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// export writes the -path subtree as it was at the -export revision to the
+// -to directory, as it is after applying any rules.
+func export(status *Status) error {
+	options := svn.ExportOptions{EOLStyle: *exportEOLStyle}
+	files, err := status.Export(*subtreePath, exportRev, *exportDir, options)
+	if err != nil {
+		return err
+	}
+	Info("Exported %d file%s from r%d to %s", files, plural(files), exportRev, *exportDir)
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// extractRange loads only the -revs range from each dump file, using their
+// indexes when -index is given, then reports on and/or writes out just those
+// revisions. Rules are not applied, since they need the full history.
+func extractRange(filenames []string) error {
+	Info("Loading r%d:%d from %d dump files", rangeFirst, rangeLast, len(filenames))
+
+	dumpfiles := make([]*svn.DumpFile, 0, len(filenames))
+	for _, filename := range filenames {
+		Log("Loading dump file: %s", filename)
+		dumpfile, err := openDumpFile(filename)
+		if err != nil {
+			return err
+		}
+		dumpfile.VerifyChecksums = *verifyChecksums
+		if err := loadDumpFileRange(dumpfile); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if len(dumpfile.Revisions) == 0 {
+			Log("%s: no revisions in range", filename)
+			continue
+		}
+		if len(dumpfiles) > 0 && (dumpfile.DumpFormat != dumpfiles[0].DumpFormat || dumpfile.UUID != dumpfiles[0].UUID) {
+			return fmt.Errorf("%w: %s: dump format/repository mismatch", svn.ErrInvalidDumpFile, filename)
+		}
+		dumpfiles = append(dumpfiles, dumpfile)
+	}
+
+	if err := reportMismatches(dumpfiles); err != nil {
+		return err
+	}
+
+	revisions := make([]*svn.Revision, 0)
+	for _, dumpfile := range dumpfiles {
+		revisions = append(revisions, dumpfile.Revisions...)
+	}
+	Info("Loaded %d revisions", len(revisions))
+
+	if *pathInfo {
+		dumpPathInfo(revisions)
+	}
+
+	if len(dumpfiles) == 0 {
+		return nil
+	}
+
+	if *outFilename != "" {
+		if err := rangeDump(*outFilename, dumpfiles); err != nil {
+			return err
+		}
+	} else if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0700); err != nil {
+			return err
+		}
+		for _, dumpfile := range dumpfiles {
+			dumpFilename := filepath.Join(*outDir, filepath.Base(dumpfile.Filename))
+			if err := rangeDump(dumpFilename, []*svn.DumpFile{dumpfile}); err != nil {
+				return err
+			}
+		}
+	}
+
+	Info("Finished")
+
+	return nil
+}
+
+// loadDumpFileRange loads the revisions of a dump file that are within the
+// -revs range, straight from its index if it has a current one.
+func loadDumpFileRange(dumpfile *svn.DumpFile) error {
+	if *useIndex {
+		err := dumpfile.LoadIndex()
+		if err == nil {
+			first, last, _ := dumpfile.IndexedRevisions()
+			if last < rangeFirst || first > rangeLast {
+				return nil
+			}
+			if first < rangeFirst {
+				first = rangeFirst
+			}
+			if last > rangeLast {
+				last = rangeLast
+			}
+			return dumpfile.LoadRevisionRange(first, last)
+		}
+		if !errors.Is(err, svn.ErrNoIndex) && !errors.Is(err, svn.ErrStaleIndex) && !errors.Is(err, svn.ErrNotSeekable) {
+			return err
+		}
+		Log("%s: %s, loading everything", dumpfile.Filename, err)
+	}
+
+	if err := dumpfile.LoadRevisions(); err != nil {
+		return err
+	}
+	if *useIndex {
+		updateIndex(dumpfile)
+	}
+
+	// Drop everything outside the range.
+	revisions := make([]*svn.Revision, 0)
+	for _, rev := range dumpfile.Revisions {
+		if rev.Number >= rangeFirst && rev.Number <= rangeLast {
+			revisions = append(revisions, rev)
+		}
+	}
+	dumpfile.Revisions = revisions
+
+	return nil
+}
+
+// updateIndex (re)writes the index for a fully loaded dump file unless it
+// already has a current one.
+func updateIndex(dumpfile *svn.DumpFile) {
+	err := dumpfile.LoadIndex()
+	if err == nil {
+		return
+	}
+	if errors.Is(err, svn.ErrNotSeekable) {
+		Log("%s: streamed dumps cannot be indexed", dumpfile.Filename)
+		return
+	}
+
+	Log("Writing index: %s", svn.IndexFilename(dumpfile.Filename))
+	if err := dumpfile.WriteIndex(); err != nil {
+		fmt.Fprintf(console, "** error writing index: %s\n", err)
+	}
+}
+
+// rangeDump writes the revisions loaded from the given dump files to a
+// single dump, which is incremental unless the range starts at r0.
+func rangeDump(filename string, dumpfiles []*svn.DumpFile) error {
+	first := dumpfiles[0].Revisions[0].Number
+	lastFile := dumpfiles[len(dumpfiles)-1]
+	last := lastFile.Revisions[len(lastFile.Revisions)-1].Number
+	Info("Dumping r%9d:%9d -> %s", first, last, filename)
+
+	return writeDump(filename, func(enc *svn.Encoder) error {
+		svn.EncodeDumpHeader(enc, dumpfiles[0].DumpFormat, dumpfiles[0].UUID)
+		for _, dumpfile := range dumpfiles {
+			for _, rev := range dumpfile.Revisions {
+				if err := rev.Encode(enc); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
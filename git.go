@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// gitOptions maps the rules' naming convention, rooted at -path, onto git refs.
+func gitOptions(status *Status) svn.GitOptions {
+	convention := status.rules.Convention
+	return svn.GitOptions{
+		Root:     *subtreePath,
+		Trunk:    convention.Trunk,
+		Branches: convention.Branches,
+		Tags:     convention.Tags,
+	}
+}
+
+// writeGit writes the history, as it is after applying any rules, as a git
+// fast-import stream to the named file, or stdout for '-'. With -git-state,
+// only revisions after those the state records are written, and the state is
+// updated.
+func writeGit(filename string, status *Status) error {
+	options := gitOptions(status)
+	if *gitStateFilename != "" {
+		state, err := readGitState(*gitStateFilename)
+		if err != nil {
+			return err
+		}
+		if state.Revision >= 0 {
+			Info("Resuming git conversion after r%d", state.Revision)
+		}
+		options.State = state
+	}
+
+	Info("Writing git fast-import stream -> %s", filename)
+	err := writeOutput(filename, func(writer io.Writer) error {
+		return status.GitFastImport(writer, options)
+	})
+	if err != nil || options.State == nil {
+		return err
+	}
+
+	return saveGitState(*gitStateFilename, options.State)
+}
+
+// readGitState loads a git conversion state, or starts a new one if the file
+// doesn't exist yet.
+func readGitState(filename string) (*svn.GitState, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return svn.NewGitState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	state, err := svn.ReadGitState(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return state, nil
+}
+
+// saveGitState replaces the state file, writing it beside the old one first
+// so an interrupted run leaves the old state intact.
+func saveGitState(filename string, state *svn.GitState) error {
+	temporary := filename + ".tmp"
+	file, err := os.OpenFile(temporary, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err = state.Write(file); err == nil {
+		err = file.Close()
+	} else {
+		_ = file.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", temporary, err)
+	}
+
+	Info("Converted up to r%d, recorded in %s", state.Revision, filename)
+	return os.Rename(temporary, filename)
+}
@@ -3,7 +3,10 @@ module github.com/kfsone/svn-go
 go 1.19
 
 require (
+	github.com/dsnet/compress v0.0.1
 	github.com/edsrzf/mmap-go v1.1.0
+	github.com/klauspost/compress v1.17.4
+	github.com/ulikunitz/xz v0.5.11
 	gopkg.in/yaml.v3 v3.0.1
 )
 
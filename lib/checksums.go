@@ -0,0 +1,75 @@
+package svn
+
+// checksums.go verifies node text against the checksum headers in the dump.
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// ChecksumMismatch records a node whose text doesn't match one of its
+// Text-content checksum headers.
+type ChecksumMismatch struct {
+	Revision int    // Revision the node belongs to.
+	Path     string // Node-path of the node.
+	Offset   int    // Offset of the node's headers in the (decompressed) dump.
+	Header   string // The checksum header that disagreed.
+	Expected string // Checksum given by the header.
+	Actual   string // Checksum of the node's text.
+}
+
+func (m ChecksumMismatch) String() string {
+	return fmt.Sprintf("r%d: %s @%d: %s mismatch: expected %s, got %s", m.Revision, m.Path, m.Offset, m.Header, m.Expected, m.Actual)
+}
+
+// checksumMismatches compares the node's text with any Text-content-md5 and
+// Text-content-sha1 headers, returning a record for each that disagrees.
+func (n *Node) checksumMismatches() ([]ChecksumMismatch, error) {
+	if !n.Headers.Has(TextContentLengthHeader) {
+		return nil, nil
+	}
+	content, err := n.Content()
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []ChecksumMismatch
+	check := func(header string, sum []byte) {
+		if expected, ok := n.Headers.table[header]; ok {
+			if actual := hex.EncodeToString(sum); actual != expected {
+				mismatches = append(mismatches, ChecksumMismatch{
+					Revision: n.Revision.Number,
+					Path:     n.Path(),
+					Offset:   n.offset,
+					Header:   header,
+					Expected: expected,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+
+	md5sum, sha1sum := md5.Sum(content), sha1.Sum(content)
+	check(TextContentMD5Header, md5sum[:])
+	check(TextContentSHA1Header, sha1sum[:])
+
+	return mismatches, nil
+}
+
+// verifyChecksums checks the text of every node in the revision, optionally
+// limited to delta or non-delta nodes, adding any mismatches to the dump file.
+func (df *DumpFile) verifyChecksums(rev *Revision, deltas bool) error {
+	for _, node := range rev.Nodes {
+		if node.textDelta != deltas {
+			continue
+		}
+		mismatches, err := node.checksumMismatches()
+		if err != nil {
+			return err
+		}
+		df.Mismatches = append(df.Mismatches, mismatches...)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package svn
+
+// compression.go lets dumps be read and written compressed. Input is
+// recognized by its leading magic bytes and output by its file extension.
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression describes a compression format dumps can be stored in.
+type Compression struct {
+	Name      string // Name of the format, e.g. "gzip".
+	Extension string // File extension, including the dot, e.g. ".gz".
+
+	magic     []byte
+	newReader func(io.Reader) (io.ReadCloser, error)
+	newWriter func(io.Writer) (io.WriteCloser, error)
+}
+
+// Compressions lists the supported compression formats.
+var Compressions = []*Compression{
+	{
+		Name:      "gzip",
+		Extension: ".gz",
+		magic:     []byte{0x1f, 0x8b},
+		newReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		newWriter: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+	},
+	{
+		Name:      "bzip2",
+		Extension: ".bz2",
+		magic:     []byte{'B', 'Z', 'h'},
+		newReader: func(r io.Reader) (io.ReadCloser, error) { return bzip2.NewReader(r, nil) },
+		newWriter: func(w io.Writer) (io.WriteCloser, error) { return bzip2.NewWriter(w, nil) },
+	},
+	{
+		Name:      "zstd",
+		Extension: ".zst",
+		magic:     []byte{0x28, 0xb5, 0x2f, 0xfd},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+	},
+	{
+		Name:      "xz",
+		Extension: ".xz",
+		magic:     []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		newReader: func(r io.Reader) (io.ReadCloser, error) {
+			reader, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(reader), nil
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) { return xz.NewWriter(w) },
+	},
+}
+
+// compressionMagicLen is enough leading bytes to recognize any format.
+const compressionMagicLen = 6
+
+// DetectCompression returns the compression format whose magic bytes begin
+// header, or nil if it doesn't look compressed.
+func DetectCompression(header []byte) *Compression {
+	for _, compression := range Compressions {
+		if bytes.HasPrefix(header, compression.magic) {
+			return compression
+		}
+	}
+	return nil
+}
+
+// CompressionForFilename returns the compression format implied by the
+// filename's extension, or nil if there isn't one.
+func CompressionForFilename(filename string) *Compression {
+	for _, compression := range Compressions {
+		if strings.HasSuffix(filename, compression.Extension) {
+			return compression
+		}
+	}
+	return nil
+}
+
+// Decompress returns a reader for the content of r, decompressing it if it
+// begins with the magic bytes of a supported format, along with the format.
+func Decompress(r io.Reader) (io.ReadCloser, *Compression, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(compressionMagicLen)
+
+	compression := DetectCompression(header)
+	if compression == nil {
+		return io.NopCloser(buffered), nil, nil
+	}
+
+	decompressor, err := compression.NewReader(buffered)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", compression.Name, err)
+	}
+
+	return decompressor, compression, nil
+}
+
+// NewReader returns a reader that decompresses r.
+func (c *Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return c.newReader(r)
+}
+
+// NewWriter returns a writer that compresses into w. It must be closed to
+// flush the compressed stream, which does not close w.
+func (c *Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return c.newWriter(w)
+}
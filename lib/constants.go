@@ -23,15 +23,56 @@ const (
 	NodeCopyfromRevHeader  = "Node-copyfrom-rev"
 	NodeCopyfromPathHeader = "Node-copyfrom-path"
 
+	TextContentMD5Header  = "Text-content-md5"
+	TextContentSHA1Header = "Text-content-sha1"
+
+	TextCopySourceMD5Header  = "Text-copy-source-md5"
+	TextCopySourceSHA1Header = "Text-copy-source-sha1"
+
+	// Format 3 (svnadmin dump --deltas) headers.
+	PropDeltaHeader         = "Prop-delta"
+	TextDeltaHeader         = "Text-delta"
+	TextDeltaBaseMD5Header  = "Text-delta-base-md5"
+	TextDeltaBaseSHA1Header = "Text-delta-base-sha1"
+
 	PropsEnd = "PROPS-END"
 )
 
+// Revision properties.
+const (
+	AuthorProperty = "svn:author"
+	DateProperty   = "svn:date"
+	LogProperty    = "svn:log"
+)
+
+// Node properties.
+const (
+	MimeTypeProperty   = "svn:mime-type"
+	MergeinfoProperty  = "svn:mergeinfo"
+	EOLStyleProperty   = "svn:eol-style"
+	ExecutableProperty = "svn:executable"
+	SpecialProperty    = "svn:special"
+)
+
+// DateLayout is the time.Time layout of svn:date values, which are always UTC.
+const DateLayout = "2006-01-02T15:04:05.000000Z"
+
 // Error types.
 var ErrDumpHeaderMismatch = errors.New("dump header mismatch")
 var ErrInvalidDumpFile = errors.New("invalid svn dump file")
 var ErrInvalidHeader = errors.New("invalid header")
+var ErrDuplicateHeader = errors.New("duplicate header")
 var ErrMissingField = errors.New("missing required field")
 var ErrMissingNewline = errors.New("missing newline")
 var ErrWindowsDumpFile = fmt.Errorf("%w: windows line-ending translations detected, on windows use `svnadmin dump -F filename` rather than redirecting output", ErrInvalidDumpFile)
+var ErrCRLFRepair = errors.New("unable to reverse line-ending translation")
 var ErrUnknownNodeKind = errors.New("unknown node kind")
 var ErrUnknownNodeAction = errors.New("unknown node action")
+var ErrInvalidDelta = errors.New("invalid svndiff data")
+var ErrDeltaBaseMismatch = errors.New("delta base checksum mismatch")
+var ErrUnresolvedDelta = errors.New("delta base has not been resolved")
+var ErrPathNotFound = errors.New("path not found")
+var ErrNotAFile = errors.New("not a file")
+var ErrExportDestination = errors.New("export destination is not empty")
+var ErrUnknownEOLStyle = errors.New("unknown svn:eol-style")
+var ErrGitState = errors.New("git conversion state doesn't match the repository")
@@ -0,0 +1,377 @@
+package svn
+
+// crlf.go repairs dumps that went through Windows newline translation, e.g.
+// by redirecting `svnadmin dump` to a file from a console, which turns every
+// \n into \r\n. Depending on the tool, a \r\n that was already in the data
+// either became \r\r\n or was left alone, so the header and property lengths
+// and the text checksums are used to decide which \r\n pairs are genuine.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// crlfSearchBudget limits how many times a \r\n pair in a file body that was
+// read as translated is reconsidered as genuine, while looking for the
+// interpretation that matches its checksums.
+const crlfSearchBudget = 1 << 16
+
+// CRLFRepair summarizes the repair of a newline-translated dump.
+type CRLFRepair struct {
+	Revisions int // Revision records written.
+	Nodes     int // Node records written.
+
+	// Ambiguous lists the node texts and properties, as "rN path: what", that
+	// decode to the right length both with and without their \r\n pairs being
+	// translated and have no checksum to settle it. They are repaired as if
+	// translated.
+	Ambiguous []string
+}
+
+type crlfRepairer struct {
+	data     []byte
+	pos      int
+	out      *bufio.Writer
+	report   *CRLFRepair
+	revision string // Number of the revision being repaired.
+	where    string // Revision and path of the record being repaired.
+}
+
+// RepairCRLF reverses the newline translation of a dump, writing the
+// corrected dump to out. Dumps without translated newlines come out as-is.
+func RepairCRLF(data []byte, out io.Writer) (*CRLFRepair, error) {
+	if !bytes.HasPrefix(data, []byte(VersionStringHeader)) {
+		return nil, ErrInvalidDumpFile
+	}
+
+	r := &crlfRepairer{
+		data:   data,
+		out:    bufio.NewWriter(out),
+		report: &CRLFRepair{},
+	}
+
+	for r.pos < len(r.data) {
+		// Newlines between records.
+		if r.consumeNewline() {
+			r.write([]byte{'\n'})
+			continue
+		}
+		if err := r.repairRecord(); err != nil {
+			return nil, fmt.Errorf("%w @%d: %s: %s", ErrCRLFRepair, r.pos, r.where, err)
+		}
+	}
+
+	if err := r.out.Flush(); err != nil {
+		return nil, err
+	}
+
+	return r.report, nil
+}
+
+// repairRecord copies one header block and any property and text blocks
+// that follow it.
+func (r *crlfRepairer) repairRecord() error {
+	headers := make(map[string]string)
+	for {
+		line, ok := r.readLine()
+		if !ok {
+			return fmt.Errorf("unterminated header block")
+		}
+		r.write([]byte(line + "\n"))
+		if line == "" {
+			break
+		}
+		key, value, err := ReadHeader([]byte(line))
+		if err != nil {
+			return err
+		}
+		headers[key] = value
+	}
+
+	if number, ok := headers[RevisionNumberHeader]; ok {
+		r.report.Revisions++
+		r.revision, r.where = number, "r"+number
+	} else if path, ok := headers[NodePathHeader]; ok {
+		r.report.Nodes++
+		r.where = "r" + r.revision + " " + path
+	}
+
+	propLen, hasProps, err := crlfLength(headers, PropContentLengthHeader)
+	if err != nil {
+		return err
+	}
+	textLen, hasText, err := crlfLength(headers, TextContentLengthHeader)
+	if err != nil {
+		return err
+	}
+	contentLen, hasContent, err := crlfLength(headers, ContentLengthHeader)
+	if err != nil {
+		return err
+	}
+
+	// Text-content-length is implied by Content-length when it's missing.
+	if !hasText && hasContent && contentLen > propLen {
+		textLen, hasText = contentLen-propLen, true
+	}
+	if hasContent && contentLen != propLen+textLen {
+		return fmt.Errorf("%s %d doesn't match the property and text lengths", ContentLengthHeader, contentLen)
+	}
+
+	if hasProps {
+		if err := r.repairProperties(propLen); err != nil {
+			return err
+		}
+	}
+	if hasText {
+		// The checksums are of the full text, so a delta can only be checked
+		// for being a well-formed svndiff.
+		if headers[TextDeltaHeader] == "true" {
+			return r.repairText(textLen, svndiffWellFormed)
+		}
+		return r.repairText(textLen, checksumVerifier(headers[TextContentMD5Header], headers[TextContentSHA1Header]))
+	}
+
+	return nil
+}
+
+// repairProperties copies a property block of length bytes once repaired,
+// working out each key and value's extent from its declared length.
+func (r *crlfRepairer) repairProperties(length int) error {
+	written := 0
+	emit := func(data []byte) {
+		r.write(data)
+		written += len(data)
+	}
+
+	for {
+		line, ok := r.readLine()
+		if !ok {
+			return fmt.Errorf("unterminated property block")
+		}
+		emit([]byte(line + "\n"))
+		if line == PropsEnd {
+			break
+		}
+		if len(line) < 3 || line[1] != ' ' || (line[0] != 'K' && line[0] != 'V' && line[0] != 'D') {
+			return fmt.Errorf("malformed property line: %q", line)
+		}
+		size, err := strconv.Atoi(line[2:])
+		if err != nil {
+			return fmt.Errorf("malformed property line: %q", line)
+		}
+
+		// A key or value is followed by a newline of its own.
+		followed := func(_ []byte, end int) bool { return r.newlineAt(end) > 0 }
+		value, end, ok := r.decodeUniform(r.pos, size, true, followed)
+		genuine, genuineEnd, genuineOk := r.decodeUniform(r.pos, size, false, followed)
+		switch {
+		case ok && genuineOk && !bytes.Equal(value, genuine):
+			r.report.Ambiguous = append(r.report.Ambiguous, fmt.Sprintf("%s: property %q", r.where, line))
+		case !ok && genuineOk:
+			value, end = genuine, genuineEnd
+		case !ok:
+			return fmt.Errorf("property data doesn't match its length: %q", line)
+		}
+
+		emit(value)
+		emit([]byte{'\n'})
+		r.pos = end + r.newlineAt(end)
+	}
+
+	if written != length {
+		return fmt.Errorf("property block repairs to %d bytes, expected %d", written, length)
+	}
+
+	return nil
+}
+
+// repairText copies a text block of length bytes once repaired. Given a way
+// to verify the text, the two uniform interpretations are tried first, then
+// every combination of translated and genuine \r\n pairs is a candidate,
+// within a budget; otherwise only the uniform interpretations are, and they
+// are checked by whether the next record follows.
+func (r *crlfRepairer) repairText(length int, verify func(text []byte) bool) error {
+	var text []byte
+	end := 0
+	if verify != nil {
+		accept := func(candidate []byte, candidateEnd int) bool {
+			if r.atRecordBoundary(candidateEnd) && verify(candidate) {
+				text, end = candidate, candidateEnd
+				return true
+			}
+			return false
+		}
+		budget := crlfSearchBudget
+		var ok bool
+		if text, end, ok = r.decodeUniform(r.pos, length, true, accept); !ok {
+			if text, end, ok = r.decodeUniform(r.pos, length, false, accept); !ok {
+				if !r.search(r.pos, length, make([]byte, 0, length), &budget, accept) {
+					return fmt.Errorf("no reading of the text at its length can be verified")
+				}
+			}
+		}
+	} else {
+		bounded := func(_ []byte, end int) bool { return r.atRecordBoundary(end) }
+		var ok bool
+		text, end, ok = r.decodeUniform(r.pos, length, true, bounded)
+		genuine, genuineEnd, genuineOk := r.decodeUniform(r.pos, length, false, bounded)
+		switch {
+		case ok && genuineOk && !bytes.Equal(text, genuine):
+			r.report.Ambiguous = append(r.report.Ambiguous, r.where+": text")
+		case !ok && genuineOk:
+			text, end = genuine, genuineEnd
+		case !ok:
+			return fmt.Errorf("text doesn't match its length")
+		}
+	}
+
+	r.write(text)
+	r.pos = end
+
+	return nil
+}
+
+// decodeUniform decodes length bytes from start, treating every \r\n pair as
+// translated or every one as genuine, and reports whether the result passes
+// check.
+func (r *crlfRepairer) decodeUniform(start, length int, translated bool, check func([]byte, int) bool) ([]byte, int, bool) {
+	if !translated {
+		end := start + length
+		if end > len(r.data) || !check(r.data[start:end], end) {
+			return nil, 0, false
+		}
+		return r.data[start:end], end, true
+	}
+
+	decoded := make([]byte, 0, length)
+	i := start
+	for len(decoded) < length && i < len(r.data) {
+		if r.pairAt(i) {
+			decoded, i = append(decoded, '\n'), i+2
+		} else {
+			decoded, i = append(decoded, r.data[i]), i+1
+		}
+	}
+	if len(decoded) < length || !check(decoded, i) {
+		return nil, 0, false
+	}
+
+	return decoded, i, true
+}
+
+// search decodes length bytes from i onto decoded, trying each \r\n pair as
+// translated before trying it as genuine, until accept takes a candidate.
+// Each retry of a pair as genuine costs one from the budget.
+func (r *crlfRepairer) search(i, length int, decoded []byte, budget *int, accept func([]byte, int) bool) bool {
+	for len(decoded) < length {
+		if i >= len(r.data) {
+			return false
+		}
+		if !r.pairAt(i) {
+			decoded, i = append(decoded, r.data[i]), i+1
+			continue
+		}
+		if r.search(i+2, length, append(decoded, '\n'), budget, accept) {
+			return true
+		}
+		if *budget <= 0 || len(decoded)+2 > length {
+			return false
+		}
+		*budget--
+		decoded, i = append(decoded, '\r', '\n'), i+2
+	}
+
+	return accept(decoded, i)
+}
+
+// atRecordBoundary reports whether offset is followed by newlines and then
+// another record or the end of the dump.
+func (r *crlfRepairer) atRecordBoundary(offset int) bool {
+	for skip := r.newlineAt(offset); skip > 0; skip = r.newlineAt(offset) {
+		offset += skip
+	}
+	rest := r.data[offset:]
+	return len(rest) == 0 ||
+		bytes.HasPrefix(rest, []byte(RevisionNumberHeader+": ")) ||
+		bytes.HasPrefix(rest, []byte(NodePathHeader+": "))
+}
+
+// write copies repaired data to the output. Errors stick to the writer and
+// are returned when it's flushed.
+func (r *crlfRepairer) write(data []byte) {
+	_, _ = r.out.Write(data)
+}
+
+func (r *crlfRepairer) pairAt(offset int) bool {
+	return offset+1 < len(r.data) && r.data[offset] == '\r' && r.data[offset+1] == '\n'
+}
+
+// newlineAt returns the length of a translated or untranslated newline at
+// offset, or 0 if there isn't one.
+func (r *crlfRepairer) newlineAt(offset int) int {
+	if r.pairAt(offset) {
+		return 2
+	}
+	if offset < len(r.data) && r.data[offset] == '\n' {
+		return 1
+	}
+	return 0
+}
+
+func (r *crlfRepairer) consumeNewline() bool {
+	skip := r.newlineAt(r.pos)
+	r.pos += skip
+	return skip > 0
+}
+
+// readLine returns the next line without its newline.
+func (r *crlfRepairer) readLine() (string, bool) {
+	eol := bytes.IndexByte(r.data[r.pos:], '\n')
+	if eol == -1 {
+		return "", false
+	}
+	line := r.data[r.pos : r.pos+eol]
+	r.pos += eol + 1
+
+	return string(bytes.TrimSuffix(line, []byte{'\r'})), true
+}
+
+// checksumVerifier returns a function checking text against whichever of the
+// md5 and sha1 checksums are given, or nil if neither is.
+func checksumVerifier(md5sum, sha1sum string) func(text []byte) bool {
+	if md5sum == "" && sha1sum == "" {
+		return nil
+	}
+	return func(text []byte) bool {
+		if md5sum != "" {
+			if sum := md5.Sum(text); hex.EncodeToString(sum[:]) != md5sum {
+				return false
+			}
+		}
+		if sha1sum != "" {
+			if sum := sha1.Sum(text); hex.EncodeToString(sum[:]) != sha1sum {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// crlfLength returns the value of a length header, and whether it was present.
+func crlfLength(headers map[string]string, key string) (int, bool, error) {
+	value, ok := headers[key]
+	if !ok {
+		return 0, false, nil
+	}
+	length, err := strconv.Atoi(value)
+	if err != nil || length < 0 {
+		return 0, false, fmt.Errorf("%w: %s: %s", ErrInvalidHeader, key, value)
+	}
+	return length, true, nil
+}
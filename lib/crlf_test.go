@@ -0,0 +1,85 @@
+package svn
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// crlfTestDump returns a dump with one file node holding text.
+func crlfTestDump(text string) []byte {
+	sum := md5.Sum([]byte(text))
+	return []byte(fmt.Sprintf("SVN-fs-dump-format-version: 2\n\n"+
+		"Revision-number: 1\nProp-content-length: 10\nContent-length: 10\n\nPROPS-END\n\n"+
+		"Node-path: file.txt\nNode-kind: file\nNode-action: add\n"+
+		"Text-content-length: %d\nText-content-md5: %s\nContent-length: %d\n\n%s\n\n",
+		len(text), hex.EncodeToString(sum[:]), len(text), text))
+}
+
+// crlfTestText returns lines of text, ending every genuine'th line with \r\n.
+func crlfTestText(lines, genuine int) string {
+	var text strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&text, "line %d", i)
+		if genuine > 0 && i%genuine == 0 {
+			text.WriteByte('\r')
+		}
+		text.WriteByte('\n')
+	}
+	return text.String()
+}
+
+func TestRepairCRLF(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		doubled bool // Genuine \r\n became \r\r\n rather than being left alone.
+	}{
+		{"empty", "", false},
+		{"one line", crlfTestText(1, 0), false},
+		{"no newline", "text", false},
+		{"100 lines", crlfTestText(100, 0), false},
+		{"70000 lines", crlfTestText(70000, 0), false},
+		{"genuine doubled", crlfTestText(100, 7), true},
+		{"70000 lines genuine doubled", crlfTestText(70000, 7), true},
+		{"genuine kept", crlfTestText(12, 5), false},
+		{"all genuine kept", crlfTestText(70000, 1), false},
+		{"70000 lines last genuine kept", crlfTestText(69999, 0) + "line 70000\r\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dump := crlfTestDump(test.text)
+			var translated []byte
+			if test.doubled {
+				translated = bytes.ReplaceAll(dump, []byte("\n"), []byte("\r\n"))
+			} else {
+				translated = bytes.ReplaceAll(dump, []byte("\r\n"), []byte("\n"))
+				translated = bytes.ReplaceAll(translated, []byte("\n"), []byte("\r\n"))
+			}
+
+			var out bytes.Buffer
+			report, err := RepairCRLF(translated, &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), dump) {
+				t.Errorf("repaired dump differs from the original")
+			}
+			if report.Revisions != 1 || report.Nodes != 1 {
+				t.Errorf("got %d revisions and %d nodes, expected 1 and 1", report.Revisions, report.Nodes)
+			}
+
+			// An untranslated dump comes out as-is.
+			out.Reset()
+			if _, err := RepairCRLF(dump, &out); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), dump) {
+				t.Errorf("untranslated dump was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package svn
+
+// diff.go produces unified diffs, in the style of `svn diff`, of the files and
+// properties in a subtree between two revisions.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// binarySniffLength is how much of a file is checked for NUL bytes to decide
+// that it isn't text, as svn does.
+const binarySniffLength = 1024
+
+var diffIndexSeparator = strings.Repeat("=", 67)
+var diffPropsSeparator = strings.Repeat("_", 67)
+
+// Diff writes a unified diff of the files and properties below path between
+// the end of revision fromRev and the end of toRev.
+func (t *Tree) Diff(w io.Writer, path string, fromRev, toRev int) error {
+	for _, rev := range []int{fromRev, toRev} {
+		if rev < 0 || rev > t.head {
+			return fmt.Errorf("revision r%d is outside r0:%d", rev, t.head)
+		}
+	}
+	path = strings.Trim(path, "/")
+	if !t.Exists(path, fromRev) && !t.Exists(path, toRev) {
+		return fmt.Errorf("%w: %s@%d or @%d", ErrPathNotFound, path, fromRev, toRev)
+	}
+
+	before, after := t.subtree(path, fromRev), t.subtree(path, toRev)
+	paths := make([]string, 0, len(before)+len(after))
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, seen := before[p]; !seen {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	out := &diffWriter{w: w, fromRev: fromRev, toRev: toRev}
+	for _, p := range paths {
+		from, to := before[p], after[p]
+		// A path that changed kind is a deletion followed by an addition.
+		if from != nil && to != nil && from.Kind != to.Kind {
+			if err := out.entry(p, from, nil); err != nil {
+				return err
+			}
+			from = nil
+		}
+		if err := out.entry(p, from, to); err != nil {
+			return err
+		}
+	}
+
+	return out.err
+}
+
+// Diff writes a unified diff of the files and properties below path between
+// two revisions. See Tree.Diff.
+func (r *Repos) Diff(w io.Writer, path string, fromRev, toRev int) error {
+	return r.Tree().Diff(w, path, fromRev, toRev)
+}
+
+// subtree returns everything at or below path at the end of rev.
+func (t *Tree) subtree(path string, rev int) map[string]*PathInfo {
+	entries := make(map[string]*PathInfo)
+	var walk func(string)
+	walk = func(p string) {
+		info, exists := t.Stat(p, rev)
+		if !exists {
+			return
+		}
+		entries[p] = info
+		if info.Kind == NodeKindDir {
+			for _, name := range t.List(p, rev) {
+				if p == "" {
+					walk(name)
+				} else {
+					walk(p + "/" + name)
+				}
+			}
+		}
+	}
+	walk(path)
+	return entries
+}
+
+type diffWriter struct {
+	w       io.Writer
+	fromRev int
+	toRev   int
+	err     error
+}
+
+func (d *diffWriter) printf(format string, args ...any) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// entry writes the differences for one path, which is absent on one side if
+// it was added or deleted.
+func (d *diffWriter) entry(path string, from, to *PathInfo) error {
+	var oldText, newText []byte
+	var oldProps, newProps *Properties
+	textChanged, binary := false, false
+
+	isFile := (from != nil && from.Kind == NodeKindFile) || (to != nil && to.Kind == NodeKindFile)
+	if from != nil {
+		oldProps = from.Properties()
+	}
+	if to != nil {
+		newProps = to.Properties()
+	}
+	if isFile && (from == nil || to == nil || from.Node() != to.Node()) {
+		var err error
+		if from != nil {
+			if oldText, err = from.Content(); err != nil {
+				return err
+			}
+		}
+		if to != nil {
+			if newText, err = to.Content(); err != nil {
+				return err
+			}
+		}
+		textChanged = from == nil || to == nil || !bytes.Equal(oldText, newText)
+		binary = isBinary(oldText, oldProps) || isBinary(newText, newProps)
+	}
+
+	propChanges := diffProperties(oldProps, newProps)
+	if !textChanged && len(propChanges) == 0 {
+		return nil
+	}
+
+	d.printf("Index: %s\n%s\n", path, diffIndexSeparator)
+	if binary {
+		d.printf("Cannot display: file marked as a binary type.\n")
+		if mimeType, ok := binaryMimeType(newProps); ok {
+			d.printf("svn:mime-type = %s\n", mimeType)
+		}
+	} else {
+		d.printf("--- %s\t(%s)\n", path, d.label(from, d.fromRev))
+		d.printf("+++ %s\t(%s)\n", path, d.label(to, d.toRev))
+		if textChanged {
+			d.hunks(splitLines(oldText), splitLines(newText), "@@", "file")
+		}
+	}
+
+	if len(propChanges) > 0 {
+		d.printf("\nProperty changes on: %s\n%s\n", path, diffPropsSeparator)
+		for _, change := range propChanges {
+			d.printf("%s: %s\n", change.verb, change.name)
+			d.hunks(splitLines(change.before), splitLines(change.after), "##", "property")
+		}
+	}
+
+	return d.err
+}
+
+func (d *diffWriter) label(info *PathInfo, rev int) string {
+	if info == nil {
+		return "nonexistent"
+	}
+	return fmt.Sprintf("revision %d", rev)
+}
+
+// hunks writes the changes between two lists of lines with diffContext lines
+// of context, using marker to bracket the hunk ranges.
+func (d *diffWriter) hunks(a, b []string, marker, what string) {
+	ops := diffLines(a, b)
+
+	for start := 0; start < len(ops); {
+		// Find the next change, and extend the hunk while the gaps between
+		// changes are small enough for their contexts to touch.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			return
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				if i-last > 2*diffContext {
+					break
+				}
+				last = i
+			}
+		}
+
+		from := first - diffContext
+		if from < start {
+			from = start
+		}
+		to := minInt(last+diffContext+1, len(ops))
+
+		oldStart, oldCount, newStart, newCount := 0, 0, 0, 0
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		d.printf("%s -%s +%s %s\n", marker, hunkRange(oldStart, oldCount), hunkRange(newStart, newCount), marker)
+		for _, op := range ops[from:to] {
+			line := op.line
+			d.printf("%c%s", op.kind, line)
+			if !strings.HasSuffix(line, "\n") {
+				d.printf("\n\\ No newline at end of %s\n", what)
+			}
+		}
+
+		start = to
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk, which counts
+// from 1 and names the line before an empty range.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines, each keeping its newline.
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// isBinary decides whether text can't be diffed, by its svn:mime-type or by
+// it containing a NUL.
+func isBinary(text []byte, props *Properties) bool {
+	if _, binary := binaryMimeType(props); binary {
+		return true
+	}
+	return bytes.IndexByte(text[:minInt(len(text), binarySniffLength)], 0) != -1
+}
+
+func binaryMimeType(props *Properties) (string, bool) {
+	if props == nil {
+		return "", false
+	}
+	mimeType, ok := props.Get(MimeTypeProperty)
+	if !ok || bytes.HasPrefix(mimeType, []byte("text/")) {
+		return "", false
+	}
+	return string(mimeType), true
+}
+
+// propertyChange describes a property that differs between two revisions.
+type propertyChange struct {
+	verb   string // Added, Modified or Deleted.
+	name   string
+	before []byte
+	after  []byte
+}
+
+// diffProperties returns the differences between two property tables, either
+// of which may be nil, sorted by name.
+func diffProperties(from, to *Properties) []propertyChange {
+	values := func(props *Properties) map[string][]byte {
+		table := make(map[string][]byte)
+		if props != nil {
+			for _, key := range props.Keys() {
+				if value, ok := props.Get(key); ok {
+					table[key] = value
+				}
+			}
+		}
+		return table
+	}
+	before, after := values(from), values(to)
+
+	var changes []propertyChange
+	for name, value := range before {
+		if newValue, ok := after[name]; !ok {
+			changes = append(changes, propertyChange{"Deleted", name, value, nil})
+		} else if !bytes.Equal(value, newValue) {
+			changes = append(changes, propertyChange{"Modified", name, value, newValue})
+		}
+	}
+	for name, value := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, propertyChange{"Added", name, nil, value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].name < changes[j].name })
+
+	return changes
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns a shortest edit script turning a into b, using the linear
+// space variant of Myers' algorithm: the middle snake of the shortest path
+// splits it into two smaller problems, so no more than two diagonal arrays
+// are needed at a time.
+func diffLines(a, b []string) []diffOp {
+	return appendEdits(make([]diffOp, 0, len(a)+len(b)), a, b)
+}
+
+// appendEdits appends a shortest edit script from a to b to ops, setting any
+// common prefix and suffix aside before splitting the rest at its middle snake.
+func appendEdits(ops []diffOp, a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		ops = appendEdits(ops, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			ops = append(ops, diffOp{' ', line})
+		}
+		ops = appendEdits(ops, a[u:], b[v:])
+	}
+
+	for _, line := range common {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the
+// middle of a shortest path from a to b, found by searching forwards from the
+// start and backwards from the end until the two searches overlap. Both a and
+// b must be non-empty.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// The furthest x reached on each diagonal, going forwards, and the
+	// furthest distance from the end reached on each diagonal, going
+	// backwards.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			if reverse := delta - k; odd && reverse >= -(d-1) && reverse <= d-1 && x+backward[offset+reverse] >= n {
+				return startX, startY, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			if reverse := delta - k; !odd && reverse >= -d && reverse <= d && x+forward[offset+reverse] >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+
+	panic("middleSnake: searches didn't meet")
+}
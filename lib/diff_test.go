@@ -0,0 +1,95 @@
+package svn
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		diagonal := 0
+		for j := range b {
+			above := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = diagonal + 1
+			} else if row[j] > row[j+1] {
+				row[j+1] = row[j]
+			}
+			diagonal = above
+		}
+	}
+	return row[len(b)]
+}
+
+// checkEdits checks that ops turns a into b with as few edits as possible.
+func checkEdits(t *testing.T, a, b []string, ops []diffOp) {
+	t.Helper()
+	var from, to []string
+	edits := 0
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			from, to = append(from, op.line), append(to, op.line)
+		case '-':
+			from, edits = append(from, op.line), edits+1
+		case '+':
+			to, edits = append(to, op.line), edits+1
+		default:
+			t.Fatalf("unexpected op %q", op.kind)
+		}
+	}
+	if fmt.Sprint(from) != fmt.Sprint(a) || fmt.Sprint(to) != fmt.Sprint(b) {
+		t.Fatalf("edit script doesn't turn a into b")
+	}
+	if minimum := len(a) + len(b) - 2*lcsLength(a, b); edits != minimum {
+		t.Errorf("%d edits, expected %d", edits, minimum)
+	}
+}
+
+// randomLines returns count lines drawn from an alphabet of distinct lines.
+func randomLines(random *rand.Rand, count, alphabet int) []string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprint(random.Intn(alphabet))
+	}
+	return lines
+}
+
+func TestDiffLines(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, size := range []struct{ a, b, alphabet int }{
+		{0, 0, 1}, {0, 5, 3}, {5, 0, 3}, {1, 1, 2}, {3, 7, 2},
+		{10, 10, 3}, {40, 25, 4}, {100, 100, 10}, {300, 280, 50},
+	} {
+		for i := 0; i < 20; i++ {
+			a := randomLines(random, size.a, size.alphabet)
+			b := randomLines(random, size.b, size.alphabet)
+			checkEdits(t, a, b, diffLines(a, b))
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	const lines = 8000
+	a, b := make([]string, lines), make([]string, lines)
+	for i := range a {
+		a[i], b[i] = fmt.Sprint("old ", i), fmt.Sprint("new ", i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ops := diffLines(a, b)
+	runtime.ReadMemStats(&after)
+
+	if len(ops) != 2*lines {
+		t.Errorf("%d ops, expected %d", len(ops), 2*lines)
+	}
+	// Space is linear in the number of lines, not in lines times edits.
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("allocated %d MiB", allocated>>20)
+	}
+}
@@ -1,6 +1,7 @@
 package svn
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -13,8 +14,19 @@ import (
 type DumpFile struct {
 	Filename  string      // Remembers the file path/name we sourced from.
 	data      mmap.MMap   // The full memory mapped view of the data.
+	source    io.Reader   // Alternatively, a stream the data is read from.
+	closers   []io.Closer // Decompressors and files to close with the stream.
 	Revisions []*Revision // Which revisions were in this file.
 
+	Compression *Compression     // Compression the source was stored with, if any.
+	index       []revisionOffset // Revision locations, from an index sidecar.
+
+	// VerifyChecksums enables checking node text against its md5/sha1 headers
+	// while loading, with any discrepancies collected in Mismatches. The text of
+	// delta nodes can only be checked once the file is added to a Repos.
+	VerifyChecksums bool
+	Mismatches      []ChecksumMismatch
+
 	DumpFormat int
 	UUID       string
 }
@@ -23,11 +35,27 @@ type DumpFile struct {
 // then consumes/checks the leading header lines that should
 // describe the dump format and UUID. The file is then ready
 // to be presented to a Repository to parse/load revisions.
+//
+// Compressed files can't be mapped, so they are streamed through
+// a decompressor instead.
 func NewDumpFile(filename string) (*DumpFile, error) {
 	file, err := os.OpenFile(filename, os.O_RDONLY, 0)
 	if err != nil {
 		return nil, err
 	}
+
+	magic := make([]byte, compressionMagicLen)
+	read, _ := file.ReadAt(magic, 0)
+	if DetectCompression(magic[:read]) != nil {
+		df, err := NewDumpStream(filename, file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		df.closers = append(df.closers, file)
+		return df, nil
+	}
+
 	defer func() {
 		if err := file.Close(); err != nil {
 			panic(fmt.Errorf("%w: closing dump file: %s", err, filename))
@@ -52,6 +80,40 @@ func NewDumpFile(filename string) (*DumpFile, error) {
 	return df, nil
 }
 
+// NewDumpStream prepares to read a dump from an arbitrary reader such as a
+// pipe, checking the leading header line without consuming it. Compressed
+// streams are detected and decompressed. Unlike a mapped file, a stream can
+// only have its revisions loaded once, and the revisions loaded from it keep
+// the data they were read from, so the whole dump ends up in memory. The
+// caller remains responsible for closing the reader.
+func NewDumpStream(name string, reader io.Reader) (*DumpFile, error) {
+	df := &DumpFile{
+		Filename:  name,
+		Revisions: make([]*Revision, 0),
+	}
+
+	decompressed, compression, err := Decompress(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	df.Compression = compression
+	df.closers = append(df.closers, decompressed)
+
+	// Peek returns what it can along with an error if the stream is shorter,
+	// which we leave to checkDumpFormat to reject.
+	buffered := bufio.NewReader(decompressed)
+	header, _ := buffered.Peek(len(VersionStringHeader) + 16)
+
+	if err := checkDumpFormat(header); err != nil {
+		_ = df.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	df.source = buffered
+
+	return df, nil
+}
+
 func (df *DumpFile) LoadRevisions() (err error) {
 	dump, err := NewDumpReader(df)
 	if err != nil {
@@ -82,6 +144,12 @@ func (df *DumpFile) LoadRevisions() (err error) {
 			return fmt.Errorf("r%d: %w", rev.Number, err)
 		}
 
+		if df.VerifyChecksums {
+			if err = df.verifyChecksums(rev, false); err != nil {
+				return fmt.Errorf("r%d: %w", rev.Number, err)
+			}
+		}
+
 		revisions = append(revisions, rev)
 	}
 
@@ -92,6 +160,15 @@ func (df *DumpFile) LoadRevisions() (err error) {
 }
 
 func (df *DumpFile) Close() error {
+	df.source = nil
+	for len(df.closers) > 0 {
+		closer := df.closers[0]
+		df.closers = df.closers[1:]
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
 	if df.data == nil {
 		return nil
 	}
@@ -2,6 +2,7 @@ package svn
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -9,12 +10,19 @@ import (
 
 type DumpReader struct {
 	*DumpFile
-	buffer []byte // Read buffer, manipulated by DumpReader.
+	buffer []byte    // Read buffer, manipulated by DumpReader.
+	source io.Reader // Streamed input, nil when the whole dump is in buffer.
+	offset int       // Offset of the read cursor from the start of the dump.
+	err    error     // First error reading from source other than EOF.
 }
 
+// streamChunkSize is the minimum amount we try to read from a streamed
+// source at once.
+const streamChunkSize = 1024 * 1024
+
 // NewDumpReader wraps a DumpFile in a DumpReader for convenience.
 func NewDumpReader(df *DumpFile) (dump *DumpReader, err error) {
-	dump = &DumpReader{DumpFile: df, buffer: df.data}
+	dump = &DumpReader{DumpFile: df, buffer: df.data, source: df.source}
 
 	// The dump format starts with two header blocks that happen to contain only
 	// one header line each.
@@ -34,6 +42,28 @@ func NewDumpReader(df *DumpFile) (dump *DumpReader, err error) {
 	return dump, nil
 }
 
+// newDumpReaderAt returns a reader positioned at the given offset of a mapped
+// dump file, having read the dump's leading header blocks.
+func newDumpReaderAt(df *DumpFile, offset int) (*DumpReader, error) {
+	if df.data == nil {
+		return nil, ErrNotSeekable
+	}
+	if offset > len(df.data) {
+		return nil, fmt.Errorf("offset %d is beyond the end of %s", offset, df.Filename)
+	}
+
+	dump, err := NewDumpReader(df)
+	if err != nil {
+		return nil, err
+	}
+	if offset < dump.offset {
+		return nil, fmt.Errorf("offset %d is within the header of %s", offset, df.Filename)
+	}
+	dump.buffer, dump.offset = df.data[offset:], offset
+
+	return dump, nil
+}
+
 func getDumpHeader[T any](dump *DumpReader, header string, converter func(string) (T, error)) (value T, err error) {
 	formatHeader, err := NewHeaders(dump)
 	if err == nil {
@@ -50,56 +80,117 @@ func getDumpHeader[T any](dump *DumpReader, header string, converter func(string
 	return
 }
 
-// Close will close the read buffer, not the underlying map. The dumpfile itself
-// must be closed discretely.
+// fill makes sure at least n bytes are buffered, reading from a streamed
+// source as required, and returns false if the input ends before that.
+//
+// Slices of the buffer are handed out to revisions and nodes, so rather than
+// ever overwriting the buffer we move the unread remainder to a new one. The
+// revisions are kept until they have all been processed and written, so the
+// consumed buffers stay in use and a streamed dump ends up in memory in full.
+// A new buffer is at least twice the size of the unread data it takes over,
+// so that a long line or block is read in a few large steps.
+func (r *DumpReader) fill(n int) bool {
+	if len(r.buffer) >= n {
+		return true
+	}
+	if r.source == nil {
+		return false
+	}
+
+	if n > cap(r.buffer) {
+		size := n
+		if size < 2*len(r.buffer) {
+			size = 2 * len(r.buffer)
+		}
+		if size < streamChunkSize {
+			size = streamChunkSize
+		}
+		buffer := make([]byte, len(r.buffer), size)
+		copy(buffer, r.buffer)
+		r.buffer = buffer
+	}
+
+	for len(r.buffer) < n {
+		read, err := r.source.Read(r.buffer[len(r.buffer):cap(r.buffer)])
+		r.buffer = r.buffer[:len(r.buffer)+read]
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				r.err = err
+			}
+			r.source = nil
+			break
+		}
+	}
+
+	return len(r.buffer) >= n
+}
+
+// eoiError returns the error to report when the input ran out: a read error
+// from the source if there was one, otherwise the given error.
+func (r *DumpReader) eoiError(err error) error {
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// Close will close the read buffer, not the underlying map or stream. The
+// dumpfile itself must be closed discretely.
 func (r *DumpReader) Close() error {
-	r.buffer = r.data[len(r.data):]
+	r.buffer = r.buffer[len(r.buffer):]
+	r.source = nil
 	return nil
 }
 
 // IsEOI returns true if the dumpfile is at the end of its input.
 func (r *DumpReader) IsEOI() bool {
-	return len(r.buffer) == 0
+	return !r.fill(1)
 }
 
 // PeekLine will return the next line of the dumpfile without advancing the read cursor
 // including the newline itself. If the reader is already at EOI, it returns io.EOF.
 // If the reader does find another newline, returns io.ErrUnexpectedEOF.
 func (r *DumpReader) PeekLine() ([]byte, error) {
-	if len(r.buffer) == 0 {
-		return nil, io.EOF
+	if !r.fill(1) {
+		return nil, r.eoiError(io.EOF)
 	}
-	eol := bytes.IndexByte(r.buffer, '\n')
-	if eol == -1 {
-		return nil, io.ErrUnexpectedEOF
+	searched := 0
+	for {
+		if eol := bytes.IndexByte(r.buffer[searched:], '\n'); eol != -1 {
+			return r.buffer[: searched+eol+1 : searched+eol+1], nil
+		}
+		searched = len(r.buffer)
+		if !r.fill(len(r.buffer) + 1) {
+			return nil, r.eoiError(io.ErrUnexpectedEOF)
+		}
 	}
-
-	return r.buffer[:eol+1], nil
 }
 
 // Peek will attempt to return the next n bytes from the reader without moving
 // the read cursor. If the buffer is at EOI, returns io.EOF, otherwise if the
 // buffer does not contain n bytes, returns io.ErrUnexpectedEOF.
 func (r *DumpReader) Peek(n int) ([]byte, error) {
-	if len(r.buffer) == 0 {
-		return nil, io.EOF
+	if !r.fill(1) {
+		return nil, r.eoiError(io.EOF)
 	}
-	if n > len(r.buffer) {
-		return nil, io.ErrUnexpectedEOF
+	if !r.fill(n) {
+		return nil, r.eoiError(io.ErrUnexpectedEOF)
 	}
 
-	return r.buffer[:n], nil
+	return r.buffer[:n:n], nil
 }
 
 // Discard advances the read cursor by upto n bytes. If the buffer contained at least
 // n bytes, returns true. Otherwise, moves the read cursor to eoi and returns false.
 func (r *DumpReader) Discard(n int) bool {
-	if n > len(r.buffer) {
+	if !r.fill(n) {
+		r.offset += len(r.buffer)
 		r.buffer = r.buffer[len(r.buffer):]
 		return false
 	}
 
 	r.buffer = r.buffer[n:]
+	r.offset += n
 	return true
 }
 
@@ -109,7 +200,7 @@ func (r *DumpReader) Discard(n int) bool {
 func (r *DumpReader) Read(n int) (data []byte, err error) {
 	if n == 0 {
 		// Return a pointer to our actual offset, but with no length.
-		return r.buffer[:0], nil
+		return r.buffer[:0:0], nil
 	}
 	if data, err = r.Peek(n); err == nil {
 		r.Discard(n)
@@ -132,10 +223,11 @@ func (r *DumpReader) ExpectAndConsume(s string) bool {
 
 // HasPrefix returns true if the read cursor begins with the given string.
 func (r *DumpReader) HasPrefix(s string) bool {
+	r.fill(len(s))
 	return bytes.HasPrefix(r.buffer, []byte(s))
 }
 
-// Tell returns the current offset of the read cursor from the start of the reader's buffer.
+// Tell returns the current offset of the read cursor from the start of the dump.
 func (r *DumpReader) Tell() int {
-	return len(r.data) - len(r.buffer)
+	return r.offset
 }
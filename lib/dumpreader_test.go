@@ -0,0 +1,40 @@
+package svn
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// countingReader counts the reads made from it.
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+func TestPeekLineLongLine(t *testing.T) {
+	line := append(bytes.Repeat([]byte("x"), 5*streamChunkSize), '\n')
+	source := &countingReader{Reader: bytes.NewReader(append(line, "next\n"...))}
+	reader := &DumpReader{DumpFile: &DumpFile{Filename: "stream"}, source: source}
+
+	peeked, err := reader.PeekLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(peeked, line) {
+		t.Fatalf("got a %d byte line, expected %d bytes", len(peeked), len(line))
+	}
+	if source.reads > 8 {
+		t.Errorf("took %d reads to buffer the line", source.reads)
+	}
+
+	reader.Discard(len(peeked))
+	if peeked, err = reader.PeekLine(); err != nil || string(peeked) != "next\n" {
+		t.Errorf("got %q, %v after the long line", peeked, err)
+	}
+}
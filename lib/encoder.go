@@ -9,6 +9,9 @@ import (
 type Encoder struct {
 	sink chan []byte
 	ok   chan bool
+
+	deltas  bool     // Write node text as svndiff against its predecessor.
+	history *history // Predecessor lookup for the repos being encoded, if any.
 }
 
 var rawWrites = flag.Bool("raw-writes", false, "use buffered io for writing dumps")
@@ -37,6 +40,13 @@ func NewEncoder(w io.Writer) *Encoder {
 	return e
 }
 
+// EnableDeltas switches the encoder to writing format 3 dumps where the text
+// of each node is an svndiff against the node's predecessor, as produced by
+// 'svnadmin dump --deltas'.
+func (e *Encoder) EnableDeltas() {
+	e.deltas = true
+}
+
 func rawWriter(w io.Writer, sink chan []byte) error {
 	for data := range sink {
 		if _, err := w.Write(data); err != nil {
@@ -0,0 +1,152 @@
+package svn
+
+// export.go writes out the files of a subtree as they were at a revision, as
+// `svn export` does, without needing a repository to check them out from.
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ExportOptions control how Tree.Export writes files.
+type ExportOptions struct {
+	EOLStyle bool   // Translate newlines as svn:eol-style asks.
+	Native   string // Newline for "native" eol-style; defaults to the platform's.
+}
+
+// symlinkPrefix starts the text of an svn:special file that is a symlink.
+const symlinkPrefix = "link "
+
+// Export writes the subtree at path, as it was at the end of revision rev, to
+// dir, which must be empty or not yet exist. A file is written into dir under
+// its own name. Files with svn:special are written as symlinks and files with
+// svn:executable are made executable. Returns the number of files written.
+func (t *Tree) Export(path string, rev int, dir string, options ExportOptions) (int, error) {
+	if rev < 0 || rev > t.head {
+		return 0, fmt.Errorf("revision r%d is outside r0:%d", rev, t.head)
+	}
+	path = strings.Trim(path, "/")
+	root, exists := t.Stat(path, rev)
+	if !exists {
+		return 0, fmt.Errorf("%w: %s@%d", ErrPathNotFound, path, rev)
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrExportDestination, dir)
+	} else if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	if options.Native == "" {
+		options.Native = "\n"
+		if runtime.GOOS == "windows" {
+			options.Native = "\r\n"
+		}
+	}
+
+	if root.Kind == NodeKindFile {
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		return 1, exportFile(root, filepath.Join(dir, name), options)
+	}
+
+	files := 0
+	var walk func(string, string) error
+	walk = func(repoPath, diskPath string) error {
+		for _, name := range t.List(repoPath, rev) {
+			// Don't let a hostile name escape the destination.
+			if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+				return fmt.Errorf("refusing to export %q in %s@%d", name, repoPath, rev)
+			}
+			childPath, childDisk := name, filepath.Join(diskPath, name)
+			if repoPath != "" {
+				childPath = repoPath + "/" + name
+			}
+			info, _ := t.Stat(childPath, rev)
+			if info.Kind == NodeKindDir {
+				if err := os.Mkdir(childDisk, 0755); err != nil {
+					return err
+				}
+				if err := walk(childPath, childDisk); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := exportFile(info, childDisk, options); err != nil {
+				return err
+			}
+			files++
+		}
+		return nil
+	}
+
+	return files, walk(path, dir)
+}
+
+// Export writes the subtree at path, as it was at the end of revision rev, to
+// dir. See Tree.Export.
+func (r *Repos) Export(path string, rev int, dir string, options ExportOptions) (int, error) {
+	return r.Tree().Export(path, rev, dir, options)
+}
+
+// exportFile writes one file, honouring its svn:special, svn:executable and,
+// if asked to, svn:eol-style properties.
+func exportFile(info *PathInfo, filename string, options ExportOptions) error {
+	content, err := info.Content()
+	if err != nil {
+		return err
+	}
+	props := info.Properties()
+
+	if _, special := props.Get(SpecialProperty); special && bytes.HasPrefix(content, []byte(symlinkPrefix)) {
+		target := string(content[len(symlinkPrefix):])
+		if err := os.Symlink(target, filename); err != nil {
+			return fmt.Errorf("%s@%d: %w", info.Path, info.Revision, err)
+		}
+		return nil
+	}
+
+	if style, ok := props.Get(EOLStyleProperty); ok && options.EOLStyle {
+		if content, err = translateEOL(content, string(style), options.Native); err != nil {
+			return fmt.Errorf("%s@%d: %w", info.Path, info.Revision, err)
+		}
+	}
+
+	mode := os.FileMode(0644)
+	if _, executable := props.Get(ExecutableProperty); executable {
+		mode = 0755
+	}
+
+	return os.WriteFile(filename, content, mode)
+}
+
+// translateEOL rewrites every newline in text, however it was written, as the
+// newline an svn:eol-style value asks for.
+func translateEOL(text []byte, style, native string) ([]byte, error) {
+	var eol string
+	switch style {
+	case "native":
+		eol = native
+	case "LF":
+		eol = "\n"
+	case "CRLF":
+		eol = "\r\n"
+	case "CR":
+		eol = "\r"
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEOLStyle, style)
+	}
+
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	text = bytes.ReplaceAll(text, []byte("\r"), []byte("\n"))
+	if eol != "\n" {
+		text = bytes.ReplaceAll(text, []byte("\n"), []byte(eol))
+	}
+	return text, nil
+}
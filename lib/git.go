@@ -0,0 +1,486 @@
+package svn
+
+// git.go writes the history of a repository as a `git fast-import` stream.
+// Each trunk, branch and tag, recognized by the folder naming convention,
+// becomes a git ref, and each revision becomes a commit on every ref whose
+// files it changed.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GitOptions describe how a repository's layout maps onto git refs.
+type GitOptions struct {
+	Root     string // Subtree holding the layout, or "" for the whole repository.
+	Trunk    string // Name of the trunk folder.
+	Branches string // Name of the folder holding branches.
+	Tags     string // Name of the folder holding tags.
+
+	TrunkBranch string // Git branch that trunk becomes; defaults to "master".
+	Domain      string // Email domain for authors; Repos.GitFastImport uses the UUID.
+
+	// Identity returns the "Name <email>" a revision's svn:author is
+	// committed as. By default an author already in that form is used as-is,
+	// and anyone else is "author <author@Domain>".
+	Identity func(author string) string
+
+	// State, if given, is where an earlier conversion left off. Only later
+	// revisions are written, and it's updated to include them.
+	State *GitState
+}
+
+// Modes of the files in a git tree.
+const (
+	gitModeFile       = "100644"
+	gitModeExecutable = "100755"
+	gitModeSymlink    = "120000"
+)
+
+type gitExporter struct {
+	tree    *Tree
+	options GitOptions
+	out     *bufio.Writer
+
+	state   *GitState
+	blobs   map[*Node]int       // Mark of the blob holding each node's text.
+	written map[*gitBranch]bool // Branches committed to by this stream.
+	pending []*gitCommit        // Commits being built for the current revision.
+}
+
+// gitBranch records the commits made for a branch root.
+type gitBranch struct {
+	ref     string
+	active  bool
+	commits []gitCommitMark
+}
+
+type gitCommitMark struct {
+	revision int
+	mark     int
+}
+
+// gitCommit is a commit being built for one branch from one revision.
+type gitCommit struct {
+	root     string
+	reset    bool     // The branch starts afresh with this commit.
+	from     int      // Mark of the parent, if not the branch's previous commit.
+	commands []string // File commands, in order.
+}
+
+// GitFastImport writes the tree's history as a `git fast-import` stream, with
+// svn:author, svn:date and svn:log becoming each commit's committer, time and
+// message. Paths that aren't in a trunk, branch or tag are left out.
+func (t *Tree) GitFastImport(w io.Writer, options GitOptions) error {
+	if options.Trunk == "" || options.Branches == "" || options.Tags == "" {
+		return fmt.Errorf("trunk, branches and tags folder names are required")
+	}
+	if options.TrunkBranch == "" {
+		options.TrunkBranch = "master"
+	}
+	options.Root = strings.Trim(options.Root, "/")
+	if options.State == nil {
+		options.State = NewGitState()
+	}
+	if options.State.Revision > t.head {
+		return fmt.Errorf("%w: already converted up to r%d, but the head is r%d", ErrGitState, options.State.Revision, t.head)
+	}
+
+	e := &gitExporter{
+		tree:    t,
+		options: options,
+		out:     bufio.NewWriter(w),
+		state:   options.State,
+		blobs:   make(map[*Node]int),
+		written: make(map[*gitBranch]bool),
+	}
+
+	for _, rev := range t.revisions[e.state.Revision+1:] {
+		if err := e.revision(rev); err != nil {
+			return fmt.Errorf("r%d: %w", rev.Number, err)
+		}
+		e.state.Revision = rev.Number
+	}
+
+	return e.out.Flush()
+}
+
+// GitFastImport writes the repository's history as a `git fast-import`
+// stream. See Tree.GitFastImport.
+func (r *Repos) GitFastImport(w io.Writer, options GitOptions) error {
+	if options.Domain == "" {
+		options.Domain = r.UUID
+	}
+	if state := options.State; state != nil {
+		if state.UUID != "" && state.UUID != r.UUID {
+			return fmt.Errorf("%w: converted from repository %s, not %s", ErrGitState, state.UUID, r.UUID)
+		}
+		state.UUID = r.UUID
+	}
+	return r.Tree().GitFastImport(w, options)
+}
+
+// branchOf returns the root of the trunk, branch or tag that path is in, and
+// the git ref it becomes.
+func (o *GitOptions) branchOf(path string) (root, ref string, ok bool) {
+	rel := path
+	if o.Root != "" {
+		if !MatchPathPrefix(path, o.Root) {
+			return "", "", false
+		}
+		rel = strings.TrimPrefix(strings.TrimPrefix(path, o.Root), "/")
+	}
+	if rel == "" {
+		return "", "", false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		project := strings.Join(parts[:i], "/")
+		if project != "" {
+			project += "/"
+		}
+		depth := i + 2
+		switch {
+		case part == o.Trunk:
+			ref, depth = "refs/heads/"+gitRefName(project+o.TrunkBranch), i+1
+		case part == o.Branches && depth <= len(parts):
+			ref = "refs/heads/" + gitRefName(project+parts[i+1])
+		case part == o.Tags && depth <= len(parts):
+			ref = "refs/tags/" + gitRefName(project+parts[i+1])
+		case part == o.Branches || part == o.Tags:
+			return "", "", false
+		default:
+			continue
+		}
+		root = strings.Join(parts[:depth], "/")
+		if o.Root != "" {
+			root = o.Root + "/" + root
+		}
+		return root, ref, true
+	}
+
+	return "", "", false
+}
+
+// mayContainBranches reports whether path is, or is an ancestor or
+// descendant of, the layout's root.
+func (o *GitOptions) mayContainBranches(path string) bool {
+	return o.Root == "" || path == "" || MatchPathPrefix(path, o.Root) || MatchPathPrefix(o.Root, path)
+}
+
+// revision adds the commits for one revision to the stream.
+func (e *gitExporter) revision(rev *Revision) error {
+	e.pending = e.pending[:0]
+
+	for _, node := range rev.Nodes {
+		path := strings.Trim(node.Path(), "/")
+		root, _, inBranch := e.options.branchOf(path)
+		fromRev, fromPath, copied := node.Branched()
+		fromPath = strings.Trim(fromPath, "/")
+
+		// Nodes at or above branch roots start and end branches.
+		if !inBranch || root == path {
+			if node.Action == NodeActionDelete || node.Action == NodeActionReplace {
+				for _, root := range e.rootsBelow(path, rev.Number-1) {
+					e.end(root)
+				}
+			}
+			if node.Action == NodeActionAdd || node.Action == NodeActionReplace {
+				if copied {
+					for _, root := range e.rootsBelow(path, rev.Number) {
+						source := fromPath + strings.TrimPrefix(root, path)
+						if err := e.start(root, rev.Number, source, fromRev); err != nil {
+							return err
+						}
+					}
+				} else if inBranch {
+					if err := e.start(root, rev.Number, "", 0); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		branch := e.state.branches[root]
+		if branch == nil || !branch.active {
+			if err := e.start(root, rev.Number, "", 0); err != nil {
+				return err
+			}
+		}
+		commit := e.commit(root)
+		rel := strings.TrimPrefix(path, root+"/")
+
+		if node.Action == NodeActionDelete || node.Action == NodeActionReplace {
+			commit.commands = append(commit.commands, "D "+gitPath(rel))
+		}
+		if node.Action == NodeActionDelete {
+			continue
+		}
+		info, exists := e.tree.Stat(path, rev.Number)
+		if !exists {
+			// Deleted by a later node in the same revision.
+			continue
+		}
+		if info.Kind == NodeKindFile {
+			if err := e.modify(commit, info, rel); err != nil {
+				return err
+			}
+		} else if copied {
+			if err := e.modifyAll(commit, path, rev.Number); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, commit := range e.pending {
+		if branch := e.state.branches[commit.root]; branch.active {
+			e.write(rev, branch, commit)
+		}
+	}
+
+	return nil
+}
+
+// rootsBelow returns the branch roots at or below path at the end of rev.
+func (e *gitExporter) rootsBelow(path string, rev int) []string {
+	if root, _, ok := e.options.branchOf(path); ok {
+		if root == path && e.tree.Exists(path, rev) {
+			return []string{path}
+		}
+		return nil
+	}
+	if !e.options.mayContainBranches(path) {
+		return nil
+	}
+
+	var roots []string
+	for _, name := range e.tree.List(path, rev) {
+		child := name
+		if path != "" {
+			child = path + "/" + name
+		}
+		roots = append(roots, e.rootsBelow(child, rev)...)
+	}
+	return roots
+}
+
+// commit returns the commit being built for a branch root.
+func (e *gitExporter) commit(root string) *gitCommit {
+	for _, commit := range e.pending {
+		if commit.root == root {
+			return commit
+		}
+	}
+	commit := &gitCommit{root: root}
+	e.pending = append(e.pending, commit)
+	return commit
+}
+
+// start begins a branch at root, as a copy of source at sourceRev if that's
+// given. Copies of another branch's root become children of its commit; other
+// copies start from scratch with the files they have.
+func (e *gitExporter) start(root string, rev int, source string, sourceRev int) error {
+	branch := e.state.branches[root]
+	if branch == nil {
+		_, ref, _ := e.options.branchOf(root)
+		branch = &gitBranch{ref: ref}
+		e.state.branches[root] = branch
+	}
+	branch.active = true
+
+	commit := e.commit(root)
+	commit.reset, commit.from, commit.commands = true, 0, nil
+
+	if source == "" {
+		return nil
+	}
+	if parent := e.state.branches[source]; parent != nil && e.tree.Exists(source, sourceRev) {
+		if commit.from = parent.commitAt(sourceRev); commit.from != 0 {
+			return nil
+		}
+	}
+
+	return e.modifyAll(commit, root, rev)
+}
+
+// end closes the branch at root, discarding anything pending for it.
+func (e *gitExporter) end(root string) {
+	if branch := e.state.branches[root]; branch != nil {
+		branch.active = false
+	}
+	for i, commit := range e.pending {
+		if commit.root == root {
+			e.pending = append(e.pending[:i], e.pending[i+1:]...)
+			break
+		}
+	}
+}
+
+// commitAt returns the mark of the branch's last commit at or before rev, or
+// 0 if it had none.
+func (b *gitBranch) commitAt(rev int) int {
+	for i := len(b.commits) - 1; i >= 0; i-- {
+		if b.commits[i].revision <= rev {
+			return b.commits[i].mark
+		}
+	}
+	return 0
+}
+
+// modifyAll adds every file at or below path at the end of rev to a commit.
+func (e *gitExporter) modifyAll(commit *gitCommit, path string, rev int) error {
+	files := e.tree.subtree(path, rev)
+	paths := make([]string, 0, len(files))
+	for p, info := range files {
+		if info.Kind == NodeKindFile {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := e.modify(commit, files[p], strings.TrimPrefix(p, commit.root+"/")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modify adds a file to a commit as rel, writing its blob if it hasn't
+// already been written.
+func (e *gitExporter) modify(commit *gitCommit, info *PathInfo, rel string) error {
+	content, err := info.Content()
+	if err != nil {
+		return err
+	}
+	props := info.Properties()
+
+	if _, special := props.Get(SpecialProperty); special && strings.HasPrefix(string(content), symlinkPrefix) {
+		target := content[len(symlinkPrefix):]
+		commit.commands = append(commit.commands,
+			fmt.Sprintf("M %s inline %s\ndata %d\n%s", gitModeSymlink, gitPath(rel), len(target), target))
+		return nil
+	}
+
+	mode := gitModeFile
+	if _, executable := props.Get(ExecutableProperty); executable {
+		mode = gitModeExecutable
+	}
+
+	mark, written := e.blobs[info.Node()]
+	if !written {
+		e.state.LastMark++
+		mark = e.state.LastMark
+		e.blobs[info.Node()] = mark
+		fmt.Fprintf(e.out, "blob\nmark :%d\ndata %d\n", mark, len(content))
+		_, _ = e.out.Write(content)
+		_ = e.out.WriteByte('\n')
+	}
+
+	commit.commands = append(commit.commands, fmt.Sprintf("M %s :%d %s", mode, mark, gitPath(rel)))
+	return nil
+}
+
+// write adds a finished commit to the stream. Errors stick to the writer and
+// are returned when it's flushed.
+func (e *gitExporter) write(rev *Revision, branch *gitBranch, commit *gitCommit) {
+	e.state.LastMark++
+	mark := e.state.LastMark
+
+	// A branch left by an earlier conversion continues from its last commit.
+	if !commit.reset && commit.from == 0 && !e.written[branch] && len(branch.commits) > 0 {
+		commit.from = branch.commits[len(branch.commits)-1].mark
+	}
+	e.written[branch] = true
+	branch.commits = append(branch.commits, gitCommitMark{rev.Number, mark})
+
+	entry := newLogEntry(rev, "", nil)
+	when := int64(0)
+	if !entry.Date.IsZero() {
+		when = entry.Date.Unix()
+	}
+
+	if commit.reset {
+		fmt.Fprintf(e.out, "reset %s\n", branch.ref)
+	}
+	fmt.Fprintf(e.out, "commit %s\nmark :%d\n", branch.ref, mark)
+	fmt.Fprintf(e.out, "committer %s %d +0000\n", e.identity(entry.Author), when)
+	fmt.Fprintf(e.out, "data %d\n%s\n", len(entry.Message), entry.Message)
+	if commit.from != 0 {
+		fmt.Fprintf(e.out, "from :%d\n", commit.from)
+	}
+	for _, command := range commit.commands {
+		fmt.Fprintf(e.out, "%s\n", command)
+	}
+	_ = e.out.WriteByte('\n')
+}
+
+// identity returns who a revision's author is committed as.
+func (e *gitExporter) identity(author string) string {
+	if e.options.Identity != nil {
+		return e.options.Identity(author)
+	}
+	if author == "" {
+		author = "(no author)"
+	}
+	if strings.Index(author, " <") > 0 && strings.HasSuffix(author, ">") {
+		return author
+	}
+	if e.options.Domain == "" {
+		return fmt.Sprintf("%s <%s>", author, author)
+	}
+	return fmt.Sprintf("%s <%s@%s>", author, author, e.options.Domain)
+}
+
+// gitPath quotes a path for a fast-import file command if it needs it. The
+// quoting is C style, as git unquotes it: backslash and double quote are
+// escaped, and control characters and non-ASCII bytes are written in octal.
+func gitPath(path string) string {
+	needsQuoting := strings.HasPrefix(path, `"`) || strings.IndexFunc(path, func(r rune) bool {
+		return r < ' ' || r == 0x7f || r == '\\'
+	}) != -1
+	if !needsQuoting {
+		return path
+	}
+
+	quoted := make([]byte, 0, len(path)+2)
+	quoted = append(quoted, '"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '"' || c == '\\':
+			quoted = append(quoted, '\\', c)
+		case c < ' ' || c >= 0x7f:
+			quoted = append(quoted, '\\', '0'+c>>6, '0'+c>>3&7, '0'+c&7)
+		default:
+			quoted = append(quoted, c)
+		}
+	}
+	return string(append(quoted, '"'))
+}
+
+// gitRefName replaces the characters git doesn't allow in ref names.
+func gitRefName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.ReplaceAll(name, "..", "_.")
+	name = strings.ReplaceAll(name, "@{", "@_")
+
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		part = strings.TrimSuffix(part, ".lock")
+		if strings.HasPrefix(part, ".") || strings.HasSuffix(part, ".") || part == "" {
+			part = "_" + strings.Trim(part, ".") + "_"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,28 @@
+package svn
+
+import (
+	"testing"
+)
+
+func TestGitPath(t *testing.T) {
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"plain/path.txt", "plain/path.txt"},
+		{"with space", "with space"},
+		{"café/日本.txt", "café/日本.txt"},
+		{`"quoted"`, `"\"quoted\""`},
+		{"line\nbreak", `"line\012break"`},
+		{"tab\there", `"tab\011here"`},
+		{"back\\slash", `"back\\slash"`},
+		{"del\x7f", `"del\177"`},
+		{"café\n\"x\"", `"caf\303\251\012\"x\""`},
+		{"日\r", `"\346\227\245\015"`},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			if got := gitPath(test.path); got != test.want {
+				t.Errorf("got %s, expected %s", got, test.want)
+			}
+		})
+	}
+}
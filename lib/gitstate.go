@@ -0,0 +1,118 @@
+package svn
+
+// gitstate.go persists how far a git conversion got, so that a later run can
+// carry on from there, adding only the new revisions to the git repository.
+//
+//	svn-go-git-state 1
+//	uuid <repository uuid>
+//	revision <last revision converted> mark <last mark used>
+//	branch <active: 0 or 1> <ref> <root path>
+//	<revision> <mark of the commit made for it>
+//	...
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const gitStateVersion = 1
+
+// GitState records where a git conversion left off: the last revision
+// converted, the last fast-import mark used, and the commits made on each
+// branch, so that new branches can be made from old commits by mark. git
+// must keep its own record of the marks, with fast-import's --export-marks
+// and --import-marks options.
+type GitState struct {
+	UUID     string // Repository converted.
+	Revision int    // Last revision converted, or -1.
+	LastMark int    // Last mark used.
+
+	branches map[string]*gitBranch
+}
+
+// NewGitState returns the state of a conversion that hasn't started.
+func NewGitState() *GitState {
+	return &GitState{Revision: -1, branches: make(map[string]*gitBranch)}
+}
+
+// ReadGitState reads a state saved by GitState.Write.
+func ReadGitState(r io.Reader) (*GitState, error) {
+	state := NewGitState()
+	in := bufio.NewReader(r)
+
+	var version int
+	if _, err := fmt.Fscanf(in, "svn-go-git-state %d\n", &version); err != nil || version != gitStateVersion {
+		return nil, fmt.Errorf("%w: unrecognized state header", ErrGitState)
+	}
+	// The UUID may be empty, which Fscanf won't match.
+	line, err := in.ReadString('\n')
+	if !strings.HasPrefix(line, "uuid ") || err != nil {
+		return nil, fmt.Errorf("%w: missing uuid", ErrGitState)
+	}
+	state.UUID = strings.TrimSuffix(strings.TrimPrefix(line, "uuid "), "\n")
+	if _, err := fmt.Fscanf(in, "revision %d mark %d\n", &state.Revision, &state.LastMark); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrGitState, err)
+	}
+
+	var branch *gitBranch
+	for lineNo := 4; ; lineNo++ {
+		line, err := in.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		fields := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 4)
+
+		switch {
+		case fields[0] == "branch" && len(fields) == 4:
+			branch = &gitBranch{ref: fields[2], active: fields[1] == "1"}
+			state.branches[fields[3]] = branch
+		case len(fields) == 2 && branch != nil:
+			var commit gitCommitMark
+			if commit.revision, err = strconv.Atoi(fields[0]); err == nil {
+				commit.mark, err = strconv.Atoi(fields[1])
+			}
+			if err != nil || commit.mark > state.LastMark {
+				return nil, fmt.Errorf("%w: line %d: bad commit: %q", ErrGitState, lineNo, line)
+			}
+			branch.commits = append(branch.commits, commit)
+		default:
+			return nil, fmt.Errorf("%w: line %d: unrecognized: %q", ErrGitState, lineNo, line)
+		}
+	}
+
+	return state, nil
+}
+
+// Write saves the state for ReadGitState.
+func (s *GitState) Write(w io.Writer) error {
+	roots := make([]string, 0, len(s.branches))
+	for root := range s.branches {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "svn-go-git-state %d\n", gitStateVersion)
+	fmt.Fprintf(out, "uuid %s\n", s.UUID)
+	fmt.Fprintf(out, "revision %d mark %d\n", s.Revision, s.LastMark)
+	for _, root := range roots {
+		branch := s.branches[root]
+		active := 0
+		if branch.active {
+			active = 1
+		}
+		fmt.Fprintf(out, "branch %d %s %s\n", active, branch.ref, root)
+		for _, commit := range branch.commits {
+			fmt.Fprintf(out, "%d %d\n", commit.revision, commit.mark)
+		}
+	}
+
+	return out.Flush()
+}
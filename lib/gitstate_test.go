@@ -0,0 +1,74 @@
+package svn
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitStateRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		state *GitState
+		want  string
+	}{
+		{"new", NewGitState(), "svn-go-git-state 1\nuuid \nrevision -1 mark 0\n"},
+		{"branches", &GitState{UUID: "7f04c085", Revision: 12, LastMark: 30, branches: map[string]*gitBranch{
+			"trunk":            {ref: "refs/heads/main", active: true, commits: []gitCommitMark{{1, 2}, {3, 5}, {12, 30}}},
+			"branches/old one": {ref: "refs/heads/old_one", commits: []gitCommitMark{{4, 8}}},
+			"tags/empty":       {ref: "refs/tags/empty", active: true},
+		}}, "svn-go-git-state 1\nuuid 7f04c085\nrevision 12 mark 30\n" +
+			"branch 0 refs/heads/old_one branches/old one\n4 8\n" +
+			"branch 1 refs/tags/empty tags/empty\n" +
+			"branch 1 refs/heads/main trunk\n1 2\n3 5\n12 30\n"},
+		{"root", &GitState{UUID: "u", Revision: 1, LastMark: 1, branches: map[string]*gitBranch{
+			"": {ref: "refs/heads/main", active: true, commits: []gitCommitMark{{1, 1}}},
+		}}, "svn-go-git-state 1\nuuid u\nrevision 1 mark 1\nbranch 1 refs/heads/main \n1 1\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := test.state.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.want {
+				t.Fatalf("got %q, expected %q", out.String(), test.want)
+			}
+
+			state, err := ReadGitState(&out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(state, test.state) {
+				t.Errorf("got %+v, expected %+v", state, test.state)
+			}
+		})
+	}
+}
+
+func TestReadGitStateInvalid(t *testing.T) {
+	const header = "svn-go-git-state 1\nuuid u\nrevision 2 mark 3\n"
+	for _, test := range []struct {
+		name  string
+		state string
+	}{
+		{"empty", ""},
+		{"not a state", "SVN-fs-dump-format-version: 2\n"},
+		{"version 2", "svn-go-git-state 2\nuuid u\nrevision 2 mark 3\n"},
+		{"missing uuid", "svn-go-git-state 1\nrevision 2 mark 3\n"},
+		{"missing revision", "svn-go-git-state 1\nuuid u\n"},
+		{"bad revision", "svn-go-git-state 1\nuuid u\nrevision x mark 3\n"},
+		{"commit before branch", header + "1 2\n"},
+		{"bad commit", header + "branch 1 refs/heads/main trunk\n1 x\n"},
+		{"unused mark", header + "branch 1 refs/heads/main trunk\n2 4\n"},
+		{"short branch", header + "branch 1 refs/heads/main\n"},
+		{"unrecognized", header + "tag x\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ReadGitState(strings.NewReader(test.state)); !errors.Is(err, ErrGitState) {
+				t.Errorf("got %v, expected ErrGitState", err)
+			}
+		})
+	}
+}
@@ -17,11 +17,7 @@ type Headers struct {
 
 // NewHeaders returns a default constructed Headers object from the given reader.
 func NewHeaders(dump *DumpReader) (h *Headers, err error) {
-	h = &Headers{
-		index:    make([]string, 0),
-		table:    make(map[string]string),
-		newlines: 0,
-	}
+	h = newHeaders(0)
 
 	for {
 		line, err := dump.PeekLine()
@@ -54,8 +50,39 @@ func NewHeaders(dump *DumpReader) (h *Headers, err error) {
 	return h, nil
 }
 
+// newHeaders returns an empty header block followed by the given number of
+// newlines.
+func newHeaders(newlines int) *Headers {
+	return &Headers{
+		index:    make([]string, 0),
+		table:    make(map[string]string),
+		newlines: newlines,
+	}
+}
+
 var headerSplit = []byte{':', ' '}
 
+// headerOrder is the order in which svnadmin writes revision and node headers.
+var headerOrder = []string{
+	RevisionNumberHeader,
+	NodePathHeader,
+	NodeKindHeader,
+	NodeActionHeader,
+	NodeCopyfromRevHeader,
+	NodeCopyfromPathHeader,
+	TextCopySourceMD5Header,
+	TextCopySourceSHA1Header,
+	PropDeltaHeader,
+	TextDeltaHeader,
+	TextDeltaBaseMD5Header,
+	TextDeltaBaseSHA1Header,
+	TextContentMD5Header,
+	TextContentSHA1Header,
+	PropContentLengthHeader,
+	TextContentLengthHeader,
+	ContentLengthHeader,
+}
+
 // ReadHeader interprets a byte slice as an RFC-822 style header and adds it to
 // the Headers index and table.
 func ReadHeader(line []byte) (key string, value string, err error) {
@@ -102,10 +129,119 @@ func (h *Headers) Len() int {
 	return len(h.index)
 }
 
+// Keys returns the names of the headers in the order they'll be written.
+func (h *Headers) Keys() []string {
+	return append([]string(nil), h.index...)
+}
+
+// Set sets a header's value. A header that isn't already present is inserted
+// where svnadmin would have placed it; headers svnadmin doesn't write go just
+// before the length headers.
 func (h *Headers) Set(key, value string) {
+	if _, present := h.table[key]; !present {
+		h.insert(h.canonicalPosition(key), key)
+	}
 	h.table[key] = value
 }
 
+// update changes the value of a header only if it's already present.
+func (h *Headers) update(key, value string) {
+	if _, present := h.table[key]; present {
+		h.table[key] = value
+	}
+}
+
+// Delete removes a header, returning false if it wasn't present.
+func (h *Headers) Delete(key string) bool {
+	idx := Index(h.index, key)
+	if idx == -1 {
+		return false
+	}
+	h.index = append(h.index[:idx], h.index[idx+1:]...)
+	delete(h.table, key)
+	return true
+}
+
+// Rename changes the name of a header, keeping its value and position.
+func (h *Headers) Rename(oldKey, newKey string) error {
+	idx := Index(h.index, oldKey)
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", ErrMissingField, oldKey)
+	}
+	if oldKey == newKey {
+		return nil
+	}
+	if _, present := h.table[newKey]; present {
+		return fmt.Errorf("%w: %s", ErrDuplicateHeader, newKey)
+	}
+	h.index[idx] = newKey
+	h.table[newKey] = h.table[oldKey]
+	delete(h.table, oldKey)
+	return nil
+}
+
+// InsertBefore sets a header and places it immediately before another,
+// moving it if it was already present.
+func (h *Headers) InsertBefore(before, key, value string) error {
+	return h.insertAt(before, 0, key, value)
+}
+
+// InsertAfter sets a header and places it immediately after another, moving
+// it if it was already present.
+func (h *Headers) InsertAfter(after, key, value string) error {
+	return h.insertAt(after, 1, key, value)
+}
+
+func (h *Headers) insertAt(anchor string, offset int, key, value string) error {
+	if key == anchor {
+		return fmt.Errorf("%w: cannot place %s relative to itself", ErrInvalidHeader, key)
+	}
+	if !h.Has(anchor) {
+		return fmt.Errorf("%w: %s", ErrMissingField, anchor)
+	}
+	h.Delete(key)
+	h.insert(Index(h.index, anchor)+offset, key)
+	h.table[key] = value
+	return nil
+}
+
+func (h *Headers) insert(pos int, key string) {
+	h.index = append(h.index, "")
+	copy(h.index[pos+1:], h.index[pos:])
+	h.index[pos] = key
+}
+
+// canonicalPosition returns where in the index svnadmin would place key.
+func (h *Headers) canonicalPosition(key string) int {
+	rank := Index(headerOrder, key)
+	// Headers svnadmin doesn't know go ahead of the lengths.
+	before := func(existingRank int) bool { return existingRank > rank }
+	if rank == -1 {
+		lengths := Index(headerOrder, PropContentLengthHeader)
+		before = func(existingRank int) bool { return existingRank >= lengths }
+	}
+
+	for pos, existing := range h.index {
+		if before(Index(headerOrder, existing)) {
+			return pos
+		}
+	}
+	return len(h.index)
+}
+
+// clone returns an independent copy of the headers.
+func (h *Headers) clone() *Headers {
+	c := &Headers{
+		index:    append(make([]string, 0, len(h.index)), h.index...),
+		table:    make(map[string]string, len(h.table)),
+		newlines: h.newlines,
+	}
+	for key, value := range h.table {
+		c.table[key] = value
+	}
+	return c
+}
+
 func (h *Headers) Encode(encoder *Encoder) {
 	// Write the headers in the original order
 	buffer := make([]byte, 0, len(h.index)*80)
@@ -0,0 +1,142 @@
+package svn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testHeaders returns headers with the given keys, in order, each having its
+// own name as its value.
+func testHeaders(keys ...string) *Headers {
+	h := newHeaders(1)
+	for _, key := range keys {
+		h.index = append(h.index, key)
+		h.table[key] = key
+	}
+	return h
+}
+
+func TestHeadersSet(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		existing []string
+		key      string
+		want     []string
+	}{
+		{"empty", nil, NodePathHeader, []string{NodePathHeader}},
+		{"between", []string{NodePathHeader, NodeActionHeader, ContentLengthHeader}, NodeKindHeader,
+			[]string{NodePathHeader, NodeKindHeader, NodeActionHeader, ContentLengthHeader}},
+		{"first", []string{NodeKindHeader, NodeActionHeader}, NodePathHeader,
+			[]string{NodePathHeader, NodeKindHeader, NodeActionHeader}},
+		{"last", []string{NodePathHeader, PropContentLengthHeader}, ContentLengthHeader,
+			[]string{NodePathHeader, PropContentLengthHeader, ContentLengthHeader}},
+		{"among lengths", []string{NodePathHeader, PropContentLengthHeader, ContentLengthHeader}, TextContentLengthHeader,
+			[]string{NodePathHeader, PropContentLengthHeader, TextContentLengthHeader, ContentLengthHeader}},
+		{"checksum before lengths", []string{NodePathHeader, TextContentLengthHeader, ContentLengthHeader}, TextContentMD5Header,
+			[]string{NodePathHeader, TextContentMD5Header, TextContentLengthHeader, ContentLengthHeader}},
+		{"unknown before lengths", []string{NodePathHeader, NodeActionHeader, PropContentLengthHeader, ContentLengthHeader}, "X-custom",
+			[]string{NodePathHeader, NodeActionHeader, "X-custom", PropContentLengthHeader, ContentLengthHeader}},
+		{"unknown without lengths", []string{NodePathHeader, NodeActionHeader}, "X-custom",
+			[]string{NodePathHeader, NodeActionHeader, "X-custom"}},
+		{"known after unknown", []string{NodePathHeader, "X-custom", ContentLengthHeader}, TextContentLengthHeader,
+			[]string{NodePathHeader, "X-custom", TextContentLengthHeader, ContentLengthHeader}},
+		{"present stays put", []string{ContentLengthHeader, NodePathHeader}, NodePathHeader,
+			[]string{ContentLengthHeader, NodePathHeader}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := testHeaders(test.existing...)
+			h.Set(test.key, "value")
+			if got := h.Keys(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+			if value, _ := h.String(test.key); value != "value" {
+				t.Errorf("got value %q", value)
+			}
+		})
+	}
+}
+
+func TestHeadersInsert(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		after  bool
+		anchor string
+		key    string
+		want   []string
+		err    error
+	}{
+		{"before first", false, "A", "X", []string{"X", "A", "B", "C"}, nil},
+		{"before last", false, "C", "X", []string{"A", "B", "X", "C"}, nil},
+		{"after first", true, "A", "X", []string{"A", "X", "B", "C"}, nil},
+		{"after last", true, "C", "X", []string{"A", "B", "C", "X"}, nil},
+		{"move before", false, "A", "C", []string{"C", "A", "B"}, nil},
+		{"move after", true, "C", "A", []string{"B", "C", "A"}, nil},
+		{"already there", true, "A", "B", []string{"A", "B", "C"}, nil},
+		{"missing anchor", false, "Z", "X", []string{"A", "B", "C"}, ErrMissingField},
+		{"self", true, "B", "B", []string{"A", "B", "C"}, ErrInvalidHeader},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := testHeaders("A", "B", "C")
+			var err error
+			if test.after {
+				err = h.InsertAfter(test.anchor, test.key, "value")
+			} else {
+				err = h.InsertBefore(test.anchor, test.key, "value")
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, expected %v", err, test.err)
+			}
+			if got := h.Keys(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+			if value, _ := h.String(test.key); test.err == nil && value != "value" {
+				t.Errorf("got value %q", value)
+			}
+		})
+	}
+}
+
+func TestHeadersRename(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		oldKey, newKey string
+		want           []string
+		err            error
+	}{
+		{"first", "A", "X", []string{"X", "B", "C"}, nil},
+		{"middle", "B", "X", []string{"A", "X", "C"}, nil},
+		{"same name", "B", "B", []string{"A", "B", "C"}, nil},
+		{"missing", "Z", "X", []string{"A", "B", "C"}, ErrMissingField},
+		{"duplicate", "A", "C", []string{"A", "B", "C"}, ErrDuplicateHeader},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := testHeaders("A", "B", "C")
+			err := h.Rename(test.oldKey, test.newKey)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, expected %v", err, test.err)
+			}
+			if got := h.Keys(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+			if test.err == nil {
+				if value, _ := h.String(test.newKey); value != test.oldKey {
+					t.Errorf("got value %q, expected %q", value, test.oldKey)
+				}
+				if test.oldKey != test.newKey && h.Has(test.oldKey) {
+					t.Errorf("%s is still present", test.oldKey)
+				}
+			}
+		})
+	}
+}
+
+func TestHeadersDelete(t *testing.T) {
+	h := testHeaders("A", "B", "C")
+	if !h.Delete("B") || h.Delete("B") {
+		t.Fatal("expected the first delete only to succeed")
+	}
+	if got := h.Keys(); !reflect.DeepEqual(got, []string{"A", "C"}) || h.Has("B") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -23,7 +23,17 @@ func Index[E comparable](s []E, e E) int {
 	return IndexFunc(s, func(x E) bool { return x == e })
 }
 
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // ReplacePathPrefixes returns the given path with any replacements defined in the ruleset.
+//
+// Deprecated: map iteration order is random, so overlapping prefixes are
+// replaced unpredictably. Call ReplacePathPrefix for each replacement in order.
 func ReplacePathPrefixes(path string, replacements map[string]string) string {
 	for prefix, replacement := range replacements {
 		if len(prefix) > 0 {
@@ -0,0 +1,253 @@
+package svn
+
+// history.go records every node action by path so that the state of any path
+// at any revision can be reconstructed, including paths that only exist
+// because an ancestor directory was copied. Tree exposes it publicly.
+
+import (
+	"sort"
+	"strings"
+)
+
+// historyEntry is a snapshot of a node's action, taken when it was recorded,
+// so that later rewrites of the node's headers don't alter history.
+type historyEntry struct {
+	seq      int64 // Orders entries: revision number << 32 | node index.
+	node     *Node
+	action   NodeAction
+	kind     NodeKind
+	copied   bool
+	copyPath string
+	copyRev  int
+	hasText  bool
+	hasProps bool
+}
+
+// pathState describes a path as it existed at some point in history.
+type pathState struct {
+	kind  NodeKind
+	text  *Node // Node that last provided the text, or nil if none.
+	props *Node // Node that last provided the property block, or nil if none.
+}
+
+type history struct {
+	entries  map[string][]*historyEntry
+	nodes    map[*Node]*historyEntry
+	children map[string]map[string]bool // Names ever recorded directly below a path.
+}
+
+func newHistory() *history {
+	return &history{
+		entries:  make(map[string][]*historyEntry),
+		nodes:    make(map[*Node]*historyEntry),
+		children: make(map[string]map[string]bool),
+	}
+}
+
+// newRevisionsHistory builds a history from the current state of the given
+// revisions' nodes.
+func newRevisionsHistory(revisions []*Revision) *history {
+	h := newHistory()
+	for _, rev := range revisions {
+		h.addRevision(rev)
+	}
+	return h
+}
+
+// addRevision records the current state of a revision's nodes.
+func (h *history) addRevision(rev *Revision) {
+	for idx, node := range rev.Nodes {
+		h.add(newHistoryEntry(node, nodeSeq(rev.Number, idx)))
+	}
+}
+
+func nodeSeq(revision, index int) int64 {
+	return int64(revision)<<32 | int64(index)
+}
+
+// revisionBound returns the sequence number immediately after the given revision.
+func revisionBound(revision int) int64 {
+	return nodeSeq(revision+1, 0)
+}
+
+func parentPath(path string) string {
+	if slash := strings.LastIndexByte(path, '/'); slash != -1 {
+		return path[:slash]
+	}
+	return ""
+}
+
+func newHistoryEntry(node *Node, seq int64) *historyEntry {
+	entry := &historyEntry{
+		seq:      seq,
+		node:     node,
+		action:   node.Action,
+		kind:     node.Kind,
+		hasText:  node.hasText(),
+		hasProps: node.hasProps(),
+	}
+	if rev, path, ok := node.Branched(); ok {
+		entry.copied, entry.copyPath, entry.copyRev = true, strings.Trim(path, "/"), rev
+	}
+	return entry
+}
+
+// add records a node. Nodes must be added in revision and node order.
+func (h *history) add(entry *historyEntry) {
+	path := strings.Trim(entry.node.Path(), "/")
+	h.entries[path] = append(h.entries[path], entry)
+	h.nodes[entry.node] = entry
+
+	for child := path; child != ""; child = parentPath(child) {
+		parent := parentPath(child)
+		names := h.children[parent]
+		if names == nil {
+			names = make(map[string]bool)
+			h.children[parent] = names
+		}
+		names[strings.TrimPrefix(child[len(parent):], "/")] = true
+	}
+}
+
+// latest returns the last entry for path before bound, optionally ignoring
+// changes, which don't define whether descendants exist.
+func (h *history) latest(path string, bound int64, definingOnly bool) *historyEntry {
+	entries := h.entries[path]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].seq >= bound }) - 1
+	for ; i >= 0; i-- {
+		if !definingOnly || entries[i].action != NodeActionChange {
+			return entries[i]
+		}
+	}
+	return nil
+}
+
+// lookup returns the state of path immediately before bound, and whether the
+// path existed at that point.
+func (h *history) lookup(path string, bound int64) (state pathState, exists bool) {
+	path = strings.Trim(path, "/")
+	own := h.latest(path, bound, false)
+
+	// An ancestor being added, deleted or replaced after the path's own last
+	// action determines whether the path exists.
+	var ancestor *historyEntry
+	var ancestorPath string
+	for p := parentPath(path); p != ""; p = parentPath(p) {
+		if entry := h.latest(p, bound, true); entry != nil && (ancestor == nil || entry.seq > ancestor.seq) {
+			ancestor, ancestorPath = entry, p
+		}
+	}
+	if ancestor != nil && (own == nil || ancestor.seq > own.seq) {
+		if ancestor.action == NodeActionDelete || !ancestor.copied {
+			return state, false
+		}
+		return h.lookup(ancestor.copyPath+path[len(ancestorPath):], revisionBound(ancestor.copyRev))
+	}
+
+	if own == nil || own.action == NodeActionDelete {
+		return state, false
+	}
+
+	if !own.hasText || !own.hasProps {
+		state, _ = h.base(own, path)
+	}
+	if own.kind != nil {
+		state.kind = own.kind
+	}
+	if own.hasText {
+		state.text = own.node
+	}
+	if own.hasProps {
+		state.props = own.node
+	}
+
+	return state, true
+}
+
+// list returns the names of the entries in the directory at path immediately
+// before bound, including those that came with a copy of it or an ancestor.
+func (h *history) list(path string, bound int64) []string {
+	path = strings.Trim(path, "/")
+	if path != "" {
+		if state, exists := h.lookup(path, bound); !exists || state.kind != NodeKindDir {
+			return nil
+		}
+	}
+
+	candidates := make(map[string]bool)
+	for name := range h.children[path] {
+		candidates[name] = true
+	}
+	if source, sourceBound, ok := h.copySource(path, bound); ok {
+		for _, name := range h.list(source, sourceBound) {
+			candidates[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		child := name
+		if path != "" {
+			child = path + "/" + name
+		}
+		if _, exists := h.lookup(child, bound); exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// copySource returns where the content of the directory at path came from, if
+// the latest addition of it or of an ancestor before bound was a copy.
+func (h *history) copySource(path string, bound int64) (string, int64, bool) {
+	defining, definingPath := h.defining(path, bound)
+	if defining == nil || !defining.copied {
+		return "", 0, false
+	}
+	return defining.copyPath + path[len(definingPath):], revisionBound(defining.copyRev), true
+}
+
+// defining returns the latest add, delete or replace before bound of path or
+// any of its ancestors, which is what determines where path's content came
+// from, along with the path it was applied to.
+func (h *history) defining(path string, bound int64) (defining *historyEntry, definingPath string) {
+	for p := path; p != ""; p = parentPath(p) {
+		if entry := h.latest(p, bound, true); entry != nil && (defining == nil || entry.seq > defining.seq) {
+			defining, definingPath = entry, p
+		}
+	}
+	return defining, definingPath
+}
+
+// properties returns the node's full property table. A node that was given
+// properties without having a block of its own adds them to those it would
+// otherwise have inherited, so they are merged with its base's.
+func (h *history) properties(node *Node) *Properties {
+	if node.Properties.raw != nil || node.Properties.Empty() {
+		return node.Properties
+	}
+
+	var base *Properties
+	if entry := h.nodes[node]; entry != nil {
+		if state, _ := h.base(entry, node.Path()); state.props != nil && state.props != node {
+			base = h.properties(state.props)
+		}
+	}
+
+	return mergeProperties(base, node.Properties)
+}
+
+// base returns the state that an entry's text and properties are relative to:
+// the copy source, or the path's prior state for a change. Plain adds and
+// replaces have no base.
+func (h *history) base(entry *historyEntry, path string) (pathState, bool) {
+	switch {
+	case entry.copied:
+		return h.lookup(entry.copyPath, revisionBound(entry.copyRev))
+	case entry.action == NodeActionChange:
+		return h.lookup(path, entry.seq)
+	}
+	return pathState{}, false
+}
@@ -0,0 +1,170 @@
+package svn
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testHistory builds a history exercising changes, copies, and deletes,
+// adds, copies and replaces made in the same revision, returning it along
+// with the nodes by "path@rev".
+func testHistory() (*history, map[string]*Node) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	nodes := make(map[string]*Node)
+	revisions := []*Revision{CreateRevision(0, "", date, "")}
+	revision := func(build func(rev *Revision)) {
+		rev := CreateRevision(len(revisions), "author", date, "")
+		build(rev)
+		for _, node := range rev.Nodes {
+			nodes[node.Path()+"@"+strconv.Itoa(rev.Number)] = node
+		}
+		revisions = append(revisions, rev)
+	}
+	copyNode := func(rev *Revision, action NodeAction, kind NodeKind, path, fromPath string, fromRev int) {
+		node := CreateNode(rev, action, kind, path)
+		node.Headers.Set(NodeCopyfromRevHeader, strconv.Itoa(fromRev))
+		node.Headers.Set(NodeCopyfromPathHeader, fromPath)
+		rev.Nodes = append(rev.Nodes, node)
+	}
+
+	revision(func(rev *Revision) {
+		rev.AddDirNode("trunk")
+		rev.AddFileNode("trunk/a", []byte("a1")).Properties.Set("p", []byte("1"))
+	})
+	revision(func(rev *Revision) {
+		// Only the properties change, and without a block of their own.
+		node := CreateNode(rev, NodeActionChange, NodeKindFile, "trunk/a")
+		node.Properties.Set("q", []byte("2"))
+		rev.Nodes = append(rev.Nodes, node)
+	})
+	revision(func(rev *Revision) {
+		copyNode(rev, NodeActionAdd, NodeKindDir, "branch", "trunk", 2)
+	})
+	revision(func(rev *Revision) {
+		copyNode(rev, NodeActionAdd, NodeKindDir, "tag", "trunk", 3)
+		rev.AddDeleteNode("tag/a")
+		rev.AddDeleteNode("trunk/a")
+		rev.AddFileNode("trunk/a", []byte("a4"))
+		copyNode(rev, NodeActionReplace, NodeKindFile, "branch/a", "trunk/a", 1)
+	})
+	revision(func(rev *Revision) {
+		rev.AddDeleteNode("branch")
+		copyNode(rev, NodeActionAdd, NodeKindDir, "branch", "tag", 4)
+		rev.AddDeleteNode("branch")
+	})
+
+	return newRevisionsHistory(revisions), nodes
+}
+
+func TestHistoryLookup(t *testing.T) {
+	h, nodes := testHistory()
+	for _, test := range []struct {
+		path   string
+		rev    int
+		exists bool
+		kind   NodeKind
+		text   string // Node providing the text, as path@rev.
+		props  string
+	}{
+		{"trunk", 0, false, nil, "", ""},
+		{"trunk", 1, true, NodeKindDir, "", "PROPS-END\n"},
+		{"missing", 1, false, nil, "", ""},
+		{"trunk/a", 1, true, NodeKindFile, "trunk/a@1", "K 1\np\nV 1\n1\nPROPS-END\n"},
+		{"/trunk/a/", 1, true, NodeKindFile, "trunk/a@1", "K 1\np\nV 1\n1\nPROPS-END\n"},
+		{"trunk/a", 2, true, NodeKindFile, "trunk/a@1", "K 1\np\nV 1\n1\nK 1\nq\nV 1\n2\nPROPS-END\n"},
+		{"branch/a", 2, false, nil, "", ""},
+		{"branch", 3, true, NodeKindDir, "", "PROPS-END\n"},
+		{"branch/a", 3, true, NodeKindFile, "trunk/a@1", "K 1\np\nV 1\n1\nK 1\nq\nV 1\n2\nPROPS-END\n"},
+		{"tag", 4, true, NodeKindDir, "", "PROPS-END\n"},
+		{"tag/a", 4, false, nil, "", ""},
+		{"trunk/a", 4, true, NodeKindFile, "trunk/a@4", "PROPS-END\n"},
+		{"branch/a", 4, true, NodeKindFile, "trunk/a@1", "K 1\np\nV 1\n1\nPROPS-END\n"},
+		{"branch", 5, false, nil, "", ""},
+		{"branch/a", 5, false, nil, "", ""},
+		{"tag", 5, true, NodeKindDir, "", "PROPS-END\n"},
+	} {
+		t.Run(test.path+"@"+strconv.Itoa(test.rev), func(t *testing.T) {
+			state, exists := h.lookup(test.path, revisionBound(test.rev))
+			if exists != test.exists {
+				t.Fatalf("got exists %v, expected %v", exists, test.exists)
+			}
+			if state.kind != test.kind {
+				t.Errorf("got kind %v, expected %v", state.kind, test.kind)
+			}
+			if state.text != nodes[test.text] {
+				t.Errorf("text came from the wrong node")
+			}
+			var props string
+			if state.props != nil {
+				props = string(h.properties(state.props).Bytes())
+			}
+			if props != test.props {
+				t.Errorf("got properties %q, expected %q", props, test.props)
+			}
+		})
+	}
+}
+
+func TestHistoryList(t *testing.T) {
+	h, _ := testHistory()
+	for _, test := range []struct {
+		path string
+		rev  int
+		want []string
+	}{
+		{"", 0, []string{}},
+		{"", 1, []string{"trunk"}},
+		{"trunk", 1, []string{"a"}},
+		{"trunk/a", 1, nil},
+		{"missing", 1, nil},
+		{"branch", 3, []string{"a"}},
+		{"", 3, []string{"branch", "trunk"}},
+		{"tag", 4, []string{}},
+		{"branch", 4, []string{"a"}},
+		{"", 5, []string{"tag", "trunk"}},
+		{"branch", 5, nil},
+	} {
+		t.Run(test.path+"@"+strconv.Itoa(test.rev), func(t *testing.T) {
+			if got := h.list(test.path, revisionBound(test.rev)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistoryBase(t *testing.T) {
+	h, nodes := testHistory()
+	for _, test := range []struct {
+		node  string
+		ok    bool
+		kind  NodeKind
+		text  string
+		props string
+	}{
+		{"trunk/a@1", false, nil, "", ""},
+		{"trunk/a@2", true, NodeKindFile, "trunk/a@1", "trunk/a@1"},
+		{"branch@3", true, NodeKindDir, "", "trunk@1"},
+		{"trunk/a@4", false, nil, "", ""},
+		{"branch/a@4", true, NodeKindFile, "trunk/a@1", "trunk/a@1"},
+		{"tag/a@4", false, nil, "", ""},
+	} {
+		t.Run(test.node, func(t *testing.T) {
+			node := nodes[test.node]
+			state, ok := h.base(h.nodes[node], node.Path())
+			if ok != test.ok {
+				t.Fatalf("got ok %v, expected %v", ok, test.ok)
+			}
+			if state.kind != test.kind {
+				t.Errorf("got kind %v, expected %v", state.kind, test.kind)
+			}
+			if state.text != nodes[test.text] {
+				t.Errorf("text came from the wrong node")
+			}
+			if state.props != nodes[test.props] {
+				t.Errorf("properties came from the wrong node")
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package svn
+
+// index.go persists the offset of every revision in a dump file to a sidecar
+// file, so that individual revisions can later be loaded without parsing
+// everything that precedes them.
+//
+//	svn-go-index 1
+//	size <dump file size> mtime <dump file modification time, unix nanoseconds>
+//	format <dump format> uuid <repository uuid>
+//	<revision number> <start offset> <end offset>
+//	...
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const indexVersion = 1
+
+// IndexSuffix is appended to a dump's filename to name its index.
+const IndexSuffix = ".idx"
+
+var ErrNoIndex = errors.New("no revision index")
+var ErrStaleIndex = errors.New("revision index does not match dump file")
+var ErrNotSeekable = errors.New("dump is streamed and cannot be randomly accessed")
+
+// revisionOffset locates one revision within a dump file.
+type revisionOffset struct {
+	number int
+	start  int
+	end    int
+}
+
+// IndexFilename returns the name of the index sidecar for a dump file.
+func IndexFilename(dumpFilename string) string {
+	return dumpFilename + IndexSuffix
+}
+
+// WriteIndex saves the offsets of the revisions loaded from a mapped dump
+// file to its index sidecar.
+func (df *DumpFile) WriteIndex() (err error) {
+	if df.data == nil {
+		return ErrNotSeekable
+	}
+	info, err := os.Stat(df.Filename)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(IndexFilename(df.Filename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	out := bufio.NewWriter(file)
+	fmt.Fprintf(out, "svn-go-index %d\n", indexVersion)
+	fmt.Fprintf(out, "size %d mtime %d\n", info.Size(), info.ModTime().UnixNano())
+	fmt.Fprintf(out, "format %d uuid %s\n", df.DumpFormat, df.UUID)
+	for _, rev := range df.Revisions {
+		fmt.Fprintf(out, "%d %d %d\n", rev.Number, rev.startOffset, rev.endOffset)
+	}
+
+	return out.Flush()
+}
+
+// LoadIndex reads the dump file's index sidecar, checking that it still
+// describes the dump file. Returns ErrNoIndex if there isn't one.
+func (df *DumpFile) LoadIndex() error {
+	if df.data == nil {
+		return ErrNotSeekable
+	}
+	info, err := os.Stat(df.Filename)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(IndexFilename(df.Filename))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoIndex
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	in := bufio.NewReader(file)
+
+	var version, dumpFormat int
+	var size, mtime int64
+	var uuid string
+	if _, err = fmt.Fscanf(in, "svn-go-index %d\n", &version); err != nil || version != indexVersion {
+		return fmt.Errorf("%w: unrecognized index header", ErrStaleIndex)
+	}
+	if _, err = fmt.Fscanf(in, "size %d mtime %d\n", &size, &mtime); err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleIndex, err)
+	}
+	if size != info.Size() || mtime != info.ModTime().UnixNano() {
+		return fmt.Errorf("%w: dump file has changed", ErrStaleIndex)
+	}
+	if _, err = fmt.Fscanf(in, "format %d uuid %s\n", &dumpFormat, &uuid); err != nil {
+		return fmt.Errorf("%w: %s", ErrStaleIndex, err)
+	}
+
+	offsets := make([]revisionOffset, 0, 4096)
+	for {
+		var offset revisionOffset
+		if _, err = fmt.Fscanf(in, "%d %d %d\n", &offset.number, &offset.start, &offset.end); err != nil {
+			break
+		}
+		if offset.start < 0 || offset.end > len(df.data) || offset.start > offset.end {
+			return fmt.Errorf("%w: r%d out of bounds", ErrStaleIndex, offset.number)
+		}
+		offsets = append(offsets, offset)
+	}
+	if len(offsets) == 0 {
+		return fmt.Errorf("%w: no revisions", ErrStaleIndex)
+	}
+
+	df.DumpFormat, df.UUID, df.index = dumpFormat, uuid, offsets
+
+	return nil
+}
+
+// IndexedRevisions returns the first and last revision numbers in the loaded
+// index, with ok false if no index has been loaded.
+func (df *DumpFile) IndexedRevisions() (first, last int, ok bool) {
+	if len(df.index) == 0 {
+		return 0, 0, false
+	}
+	return df.index[0].number, df.index[len(df.index)-1].number, true
+}
+
+// LoadRevision loads a single revision using the index, and appends it to
+// the file's revisions.
+func (df *DumpFile) LoadRevision(number int) (*Revision, error) {
+	if err := df.LoadRevisionRange(number, number); err != nil {
+		return nil, err
+	}
+	return df.Revisions[len(df.Revisions)-1], nil
+}
+
+// LoadRevisionRange loads revisions first through last, inclusive, using the
+// index to find them, and appends them to the file's revisions. Text deltas
+// in the range can't be resolved unless every revision is loaded.
+func (df *DumpFile) LoadRevisionRange(first, last int) (err error) {
+	if len(df.index) == 0 {
+		return ErrNoIndex
+	}
+	lo, hi, _ := df.IndexedRevisions()
+	if first > last || first < lo || last > hi {
+		return fmt.Errorf("revision range r%d:%d is outside r%d:%d in %s", first, last, lo, hi, df.Filename)
+	}
+
+	at := sort.Search(len(df.index), func(i int) bool { return df.index[i].number >= first })
+
+	dump, err := newDumpReaderAt(df, df.index[at].start)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dump.Close(); err != nil {
+			panic(fmt.Errorf("closing reader: %w", err))
+		}
+	}()
+
+	revisions := make([]*Revision, 0, last-first+1)
+	for _, offset := range df.index[at:] {
+		if offset.number > last {
+			break
+		}
+		rev, err := NewRevision(dump)
+		if err != nil {
+			return fmt.Errorf("r%d: %w", offset.number, err)
+		}
+		if rev.Number != offset.number || rev.startOffset != offset.start {
+			return fmt.Errorf("%w: expected r%d at %d, found r%d", ErrStaleIndex, offset.number, offset.start, rev.Number)
+		}
+		if err = rev.Load(); err != nil {
+			return fmt.Errorf("r%d: %w", rev.Number, err)
+		}
+		if df.VerifyChecksums {
+			if err = df.verifyChecksums(rev, false); err != nil {
+				return fmt.Errorf("r%d: %w", rev.Number, err)
+			}
+		}
+		revisions = append(revisions, rev)
+	}
+
+	df.Revisions = append(df.Revisions, revisions...)
+
+	return nil
+}
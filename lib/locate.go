@@ -0,0 +1,70 @@
+package svn
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sections of a dump that an offset can fall within.
+const (
+	SectionDumpHeader = "dump header"
+	SectionHeaders    = "headers"
+	SectionProperties = "properties"
+	SectionText       = "text"
+)
+
+// Location describes what was at a given offset of a dump file when it was
+// loaded.
+type Location struct {
+	Offset   int
+	Revision *Revision // nil within the dump's leading header blocks.
+	Node     *Node     // nil within the revision's own headers or properties.
+	Section  string    // One of the Section constants.
+}
+
+func (l Location) String() string {
+	switch {
+	case l.Revision == nil:
+		return fmt.Sprintf("%s @%d", l.Section, l.Offset)
+	case l.Node == nil:
+		return fmt.Sprintf("r%d revision %s @%d", l.Revision.Number, l.Section, l.Offset)
+	default:
+		return fmt.Sprintf("r%d node #%d (%s) %s @%d", l.Revision.Number, Index(l.Revision.Nodes, l.Node), l.Node.Path(), l.Section, l.Offset)
+	}
+}
+
+// Locate returns the revision, node and section that were at an offset of
+// the dump file as it was loaded. Revisions and nodes removed since loading
+// aren't found.
+func (df *DumpFile) Locate(offset int) Location {
+	location := Location{Offset: offset, Section: SectionDumpHeader}
+
+	revNo := sort.Search(len(df.Revisions), func(i int) bool { return df.Revisions[i].startOffset > offset }) - 1
+	if revNo < 0 {
+		return location
+	}
+	rev := df.Revisions[revNo]
+	location.Revision = rev
+
+	nodeNo := sort.Search(len(rev.Nodes), func(i int) bool { return rev.Nodes[i].offset > offset }) - 1
+	if nodeNo < 0 {
+		location.Section = SectionHeaders
+		if offset >= rev.propsOffset {
+			location.Section = SectionProperties
+		}
+		return location
+	}
+	node := rev.Nodes[nodeNo]
+	location.Node = node
+
+	switch {
+	case offset < node.propsOffset:
+		location.Section = SectionHeaders
+	case offset < node.textOffset:
+		location.Section = SectionProperties
+	default:
+		location.Section = SectionText
+	}
+
+	return location
+}
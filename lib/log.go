@@ -0,0 +1,94 @@
+package svn
+
+// log.go lists the revisions that touched a path, following it back through
+// the copies it was created by, as `svn log` does without --stop-on-copy.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogEntry describes a revision that touched a path.
+type LogEntry struct {
+	Revision int
+	Author   string
+	Date     time.Time // Zero if the revision has no svn:date.
+	Message  string
+	Path     string  // What the path was called in this revision.
+	Changes  []*Node // Nodes in the revision that affected the path.
+}
+
+// Log returns the revisions between fromRev and toRev that touched path, or
+// anything below it, newest first. The path is the one that exists at toRev,
+// and it is followed back through the copies that created it or its parents,
+// stopping where it was first added.
+func (t *Tree) Log(path string, fromRev, toRev int) ([]*LogEntry, error) {
+	if fromRev < 0 || toRev > t.head || fromRev > toRev {
+		return nil, fmt.Errorf("invalid revision range r%d:%d of r0:%d", fromRev, toRev, t.head)
+	}
+	path = strings.Trim(path, "/")
+	if !t.Exists(path, toRev) {
+		return nil, fmt.Errorf("%w: %s@%d", ErrPathNotFound, path, toRev)
+	}
+
+	entries := make([]*LogEntry, 0)
+	for number := toRev; number >= fromRev; {
+		rev := t.revisions[number]
+		if changes := changesAffecting(rev, path); len(changes) > 0 {
+			entries = append(entries, newLogEntry(rev, path, changes))
+		}
+
+		// Where the path came into being in this revision, carry on from
+		// whatever it was copied from, or stop if it wasn't a copy.
+		defining, definingPath := t.history.defining(path, revisionBound(number))
+		if defining != nil && defining.seq >= nodeSeq(number, 0) {
+			if !defining.copied || defining.copyRev >= number {
+				break
+			}
+			path = defining.copyPath + path[len(definingPath):]
+			number = defining.copyRev
+			continue
+		}
+
+		number--
+	}
+
+	return entries, nil
+}
+
+// Log returns the revisions between fromRev and toRev that touched path,
+// following it back through copies. See Tree.Log.
+func (r *Repos) Log(path string, fromRev, toRev int) ([]*LogEntry, error) {
+	return r.Tree().Log(path, fromRev, toRev)
+}
+
+// changesAffecting returns the revision's nodes that act on path, anything
+// below it, or an ancestor in a way that replaces path.
+func changesAffecting(rev *Revision, path string) []*Node {
+	var changes []*Node
+	for _, node := range rev.Nodes {
+		nodePath := strings.Trim(node.Path(), "/")
+		switch {
+		case path == "" || nodePath == path || strings.HasPrefix(nodePath, path+"/"):
+			changes = append(changes, node)
+		case strings.HasPrefix(path, nodePath+"/") && node.Action != NodeActionChange:
+			changes = append(changes, node)
+		}
+	}
+	return changes
+}
+
+func newLogEntry(rev *Revision, path string, changes []*Node) *LogEntry {
+	entry := &LogEntry{Revision: rev.Number, Path: path, Changes: changes}
+	if author, ok := rev.Properties.Get(AuthorProperty); ok {
+		entry.Author = string(author)
+	}
+	if message, ok := rev.Properties.Get(LogProperty); ok {
+		entry.Message = string(message)
+	}
+	if date, ok := rev.Properties.Get(DateProperty); ok {
+		entry.Date, _ = time.Parse(DateLayout, string(date))
+	}
+	return entry
+}
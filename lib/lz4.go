@@ -0,0 +1,81 @@
+package svn
+
+import "fmt"
+
+// lz4DecodeBlock expands a raw lz4 block (no frame header), as used by
+// svndiff version 2, which must produce exactly size bytes.
+//
+//	sequence := token literal-length* literals offset match-length*
+//
+// The high nibble of the token is the literal count and the low nibble is the
+// match length minus 4; a nibble of 15 is extended by following bytes until
+// one is not 255. The final sequence of a block has only literals.
+func lz4DecodeBlock(src []byte, size int) ([]byte, error) {
+	// No block expands more than 255-fold, so don't trust a size beyond that.
+	dst := make([]byte, 0, minInt(size, 255*len(src)))
+
+	readLength := func(i, length int) (int, int, error) {
+		if length != 15 {
+			return i, length, nil
+		}
+		for {
+			if i >= len(src) {
+				return 0, 0, fmt.Errorf("%w: truncated lz4 length", ErrInvalidDelta)
+			}
+			b := src[i]
+			i++
+			length += int(b)
+			if b != 255 {
+				return i, length, nil
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		var literals int
+		var err error
+		if i, literals, err = readLength(i, int(token>>4)); err != nil {
+			return nil, err
+		}
+		if i+literals > len(src) {
+			return nil, fmt.Errorf("%w: lz4 literals overrun block", ErrInvalidDelta)
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		// The last sequence has no match part.
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, fmt.Errorf("%w: truncated lz4 offset", ErrInvalidDelta)
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, fmt.Errorf("%w: invalid lz4 offset %d", ErrInvalidDelta, offset)
+		}
+
+		var match int
+		if i, match, err = readLength(i, int(token&0x0f)); err != nil {
+			return nil, err
+		}
+		match += 4
+
+		// Matches may overlap the bytes they produce.
+		from := len(dst) - offset
+		for j := 0; j < match; j++ {
+			dst = append(dst, dst[from+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, fmt.Errorf("%w: lz4 block decoded to %d bytes, expected %d", ErrInvalidDelta, len(dst), size)
+	}
+
+	return dst, nil
+}
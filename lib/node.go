@@ -1,8 +1,12 @@
 package svn
 
 import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strconv"
 )
 
 type Node struct {
@@ -13,7 +17,15 @@ type Node struct {
 	Action NodeAction // Action taken on the node (add/change/delete/replace).
 	Kind   NodeKind   // Kind of node (file/dir).
 
-	data []byte // Raw binary data for the node.
+	data        []byte // Raw binary data for the node.
+	offset      int    // Offset of the node's headers in the dump it was read from.
+	propsOffset int    // Offset of the node's property block in that dump.
+	textOffset  int    // Offset of the node's text in that dump.
+
+	textDelta    bool   // data is an svndiff against deltaBase's content.
+	deltaBase    *Node  // Node providing the delta source text, nil if empty.
+	baseResolved bool   // Whether deltaBase has been determined.
+	content      []byte // Reconstructed text of a delta node.
 
 	newlines int
 }
@@ -21,6 +33,7 @@ type Node struct {
 func NewNode(rev *Revision) (nodePtr *Node, err error) {
 	node := &Node{
 		Revision: rev,
+		offset:   rev.dump.Tell(),
 	}
 
 	if node.Headers, err = NewHeaders(rev.dump); err != nil {
@@ -39,9 +52,11 @@ func NewNode(rev *Revision) (nodePtr *Node, err error) {
 	propLen, _ := node.Headers.Int(PropContentLengthHeader)
 	bodyLen, _ := node.Headers.Int(TextContentLengthHeader)
 
+	node.propsOffset = rev.dump.Tell()
 	if node.Properties, err = NewProperties(rev.dump, propLen); err != nil {
 		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	node.textOffset = rev.dump.Tell()
 
 	if err = node.Properties.Load(); err != nil {
 		return nil, fmt.Errorf("%s: %w", path, err)
@@ -51,8 +66,13 @@ func NewNode(rev *Revision) (nodePtr *Node, err error) {
 		if node.data, err = rev.dump.Read(bodyLen); err != nil {
 			return nil, fmt.Errorf("%s: content: %w", path, err)
 		}
+	} else if node.Headers.Has(TextContentLengthHeader) {
+		// An empty text block still replaces the text.
+		node.data = []byte{}
 	}
 
+	node.textDelta = node.Headers.Has(TextContentLengthHeader) && node.Headers.table[TextDeltaHeader] == "true"
+
 	for rev.dump.ExpectAndConsume("\n") {
 		node.newlines++
 	}
@@ -60,6 +80,78 @@ func NewNode(rev *Revision) (nodePtr *Node, err error) {
 	return node, nil
 }
 
+// CreateNode returns a node that wasn't read from a dump, with just the
+// Node-path, Node-kind and Node-action headers, ready to be given properties
+// and content. Deletions have no kind, so kind may be nil. The node still has
+// to be added to a revision's Nodes.
+func CreateNode(rev *Revision, action NodeAction, kind NodeKind, path string) *Node {
+	node := &Node{
+		Revision:   rev,
+		Headers:    newHeaders(1),
+		Properties: &Properties{index: make([]string, 0), table: make(map[string][]byte)},
+		Action:     action,
+		Kind:       kind,
+		newlines:   1,
+	}
+
+	node.Headers.Set(NodePathHeader, path)
+	if kind != nil {
+		node.Headers.Set(NodeKindHeader, *kind)
+	}
+	for name, nodeAction := range NodeActions {
+		if nodeAction == action {
+			node.Headers.Set(NodeActionHeader, name)
+		}
+	}
+
+	return node
+}
+
+// AddDirNode appends a node adding a directory, with an empty property block.
+func (r *Revision) AddDirNode(path string) *Node {
+	node := CreateNode(r, NodeActionAdd, NodeKindDir, path)
+	node.setEmptyProperties()
+	r.Nodes = append(r.Nodes, node)
+	return node
+}
+
+// AddFileNode appends a node adding a file with the given content and an
+// empty property block.
+func (r *Revision) AddFileNode(path string, content []byte) *Node {
+	node := CreateNode(r, NodeActionAdd, NodeKindFile, path)
+	node.setEmptyProperties()
+	_ = node.SetContent(content)
+	r.Nodes = append(r.Nodes, node)
+	return node
+}
+
+// AddCopyNode appends a node adding path as a copy of fromPath as it was in
+// fromRev. The copy inherits its source's properties and text unless it is
+// given its own.
+func (r *Revision) AddCopyNode(path string, kind NodeKind, fromPath string, fromRev int) *Node {
+	node := CreateNode(r, NodeActionAdd, kind, path)
+	node.Headers.Set(NodeCopyfromRevHeader, strconv.Itoa(fromRev))
+	node.Headers.Set(NodeCopyfromPathHeader, fromPath)
+	r.Nodes = append(r.Nodes, node)
+	return node
+}
+
+// AddDeleteNode appends a node deleting path, and anything below it.
+func (r *Revision) AddDeleteNode(path string) *Node {
+	node := CreateNode(r, NodeActionDelete, nil, path)
+	r.Nodes = append(r.Nodes, node)
+	return node
+}
+
+// setEmptyProperties gives the node an empty property block, which replaces
+// any properties it would otherwise have had.
+func (n *Node) setEmptyProperties() {
+	n.Properties = newEmptyProperties()
+	n.Headers.Set(PropContentLengthHeader, strconv.Itoa(len(propertiesSuffix)))
+	n.Headers.Set(ContentLengthHeader, strconv.Itoa(len(propertiesSuffix)+len(n.data)))
+	n.newlines = 2
+}
+
 func checkNodeHeaders(node *Node) (err error) {
 	action, err := node.Headers.String(NodeActionHeader)
 	if err != nil {
@@ -123,24 +215,215 @@ func (n *Node) Branched() (revision int, path string, ok bool) {
 	return
 }
 
-func (n *Node) Encode(encoder *Encoder) {
-	// Re-encode the properties blob so we can get the length.
-	properties := n.Properties.Bytes()
+// hasText returns true if the node has a text block, even an empty one.
+func (n *Node) hasText() bool {
+	return n.data != nil
+}
+
+// hasProps returns true if the node has a property block, or has been given
+// properties to add to those it inherits.
+func (n *Node) hasProps() bool {
+	return n.Properties.raw != nil || !n.Properties.Empty()
+}
+
+// IsDelta returns true if the node's text or properties are expressed as
+// changes against a predecessor, as in a format 3 dump.
+func (n *Node) IsDelta() bool {
+	return n.textDelta || n.Headers.table[PropDeltaHeader] == "true"
+}
+
+// Content returns the full text of the node. For nodes loaded from a delta
+// dump this is reconstructed from the delta base, which requires that the
+// node has been added to a Repos. Nodes without a text block return nil.
+func (n *Node) Content() ([]byte, error) {
+	if !n.textDelta {
+		return n.data, nil
+	}
+	if n.content != nil {
+		return n.content, nil
+	}
+	if !n.baseResolved {
+		return nil, fmt.Errorf("%s: %w", n.Path(), ErrUnresolvedDelta)
+	}
+
+	var base []byte
+	if n.deltaBase != nil {
+		var err error
+		if base, err = n.deltaBase.Content(); err != nil {
+			return nil, err
+		}
+	}
+
+	if expected, ok := n.Headers.table[TextDeltaBaseMD5Header]; ok {
+		sum := md5.Sum(base)
+		if actual := hex.EncodeToString(sum[:]); actual != expected {
+			return nil, fmt.Errorf("%s: %w: expected %s, got %s", n.Path(), ErrDeltaBaseMismatch, expected, actual)
+		}
+	}
+
+	content, err := ApplySvndiff(base, n.data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.Path(), err)
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	n.content = content
+
+	return content, nil
+}
+
+// setDeltaBase records the node whose text this node's delta applies to.
+func (n *Node) setDeltaBase(base *Node) {
+	n.deltaBase, n.baseResolved = base, true
+}
+
+// expandTextDelta replaces a delta-encoded text block with the full text.
+func (n *Node) expandTextDelta() error {
+	content, err := n.Content()
+	if err != nil {
+		return err
+	}
+
+	return n.SetContent(content)
+}
+
+// SetContent replaces the node's text, updating the Text-content-length and
+// Text-content-md5/sha1 headers to match and adding them if the node had no
+// text block. Only file nodes that aren't being deleted can carry text.
+func (n *Node) SetContent(content []byte) error {
+	if n.Kind != NodeKindFile || n.Action == NodeActionDelete {
+		return fmt.Errorf("%s: only files can have content", n.Path())
+	}
+	if content == nil {
+		content = []byte{}
+	}
+
+	n.data, n.content, n.textDelta, n.deltaBase, n.baseResolved = content, nil, false, nil, false
+	n.Headers.Delete(TextDeltaHeader)
+	n.Headers.Delete(TextDeltaBaseMD5Header)
+	n.Headers.Delete(TextDeltaBaseSHA1Header)
 
-	// Update the length headers accordingly.
-	n.Headers.Set(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
-	n.Headers.Set(ContentLengthHeader, fmt.Sprintf("%d", len(properties)+len(n.data)))
+	md5sum, sha1sum := md5.Sum(content), sha1.Sum(content)
+	n.Headers.Set(TextContentMD5Header, hex.EncodeToString(md5sum[:]))
+	n.Headers.Set(TextContentSHA1Header, hex.EncodeToString(sha1sum[:]))
+	n.Headers.Set(TextContentLengthHeader, fmt.Sprintf("%d", len(content)))
+	n.Headers.Set(ContentLengthHeader, fmt.Sprintf("%d", len(n.Properties.Bytes())+len(content)))
+
+	// Content is followed by a blank line.
+	if n.newlines < 2 {
+		n.newlines = 2
+	}
+
+	return nil
+}
+
+// expandPropDelta replaces a delta property block with the full property
+// table, given the properties of the node it was relative to (nil if none).
+func (n *Node) expandPropDelta(base *Properties) {
+	n.Properties = mergeProperties(base, n.Properties)
+	n.Headers.Delete(PropDeltaHeader)
+}
+
+// deltaEncode returns a copy of the node's headers and its text expressed as
+// an svndiff against whatever the node's predecessor in hist was.
+func (n *Node) deltaEncode(hist *history) (*Headers, []byte, error) {
+	var base *Node
+	if entry := hist.nodes[n]; entry != nil {
+		state, _ := hist.base(entry, n.Path())
+		base = state.text
+	}
+
+	var source []byte
+	if base != nil {
+		var err error
+		if source, err = base.Content(); err != nil {
+			return nil, nil, fmt.Errorf("%s: delta base: %w", n.Path(), err)
+		}
+	}
+
+	delta := EncodeSvndiff(source, n.data)
+
+	headers := n.Headers.clone()
+	headers.Set(TextDeltaHeader, "true")
+	if base != nil {
+		md5sum, sha1sum := md5.Sum(source), sha1.Sum(source)
+		headers.Set(TextDeltaBaseMD5Header, hex.EncodeToString(md5sum[:]))
+		headers.Set(TextDeltaBaseSHA1Header, hex.EncodeToString(sha1sum[:]))
+	}
+	headers.update(TextContentLengthHeader, fmt.Sprintf("%d", len(delta)))
+
+	return headers, delta, nil
+}
+
+// Encode writes the node to the encoder. It fails if the node's text is a delta
+// that can't be expanded, or can't be delta encoded because its predecessor's
+// can't.
+func (n *Node) Encode(encoder *Encoder) error {
+	// A deletion has no property or text block, whatever has been done to its
+	// Properties.
+	if n.Action == NodeActionDelete {
+		headers := n.Headers.clone()
+		headers.Delete(PropContentLengthHeader)
+		headers.Delete(TextContentLengthHeader)
+		headers.Delete(ContentLengthHeader)
+		headers.Encode(encoder)
+		encoder.Newlines(n.newlines)
+		return nil
+	}
+
+	// Delta dumps are re-encoded as full text, unless the node's delta base
+	// isn't available, e.g. when only part of a dump was loaded, in which case
+	// it goes out verbatim.
+	if n.textDelta && n.baseResolved {
+		if err := n.expandTextDelta(); err != nil {
+			return err
+		}
+	}
+
+	headers, data := n.Headers, n.data
+	if n.Headers.Has(TextContentLengthHeader) {
+		if encoder.deltas && encoder.history != nil && !n.textDelta {
+			var err error
+			if headers, data, err = n.deltaEncode(encoder.history); err != nil {
+				return err
+			}
+		} else {
+			headers.update(TextContentLengthHeader, fmt.Sprintf("%d", len(data)))
+		}
+	}
+
+	// Re-encode the properties blob so we can get the length. A block written
+	// out replaces the properties the node would have inherited, so any it
+	// was given without one have to be merged into those first.
+	props := n.Properties
+	if encoder.history != nil {
+		props = encoder.history.properties(n)
+	}
+	properties := props.Bytes()
+
+	// Update the length headers accordingly, adding them if the node has
+	// gained a property block.
+	if len(properties) > 0 {
+		headers.Set(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+		headers.Set(ContentLengthHeader, fmt.Sprintf("%d", len(properties)+len(data)))
+	} else {
+		headers.update(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+		headers.update(ContentLengthHeader, fmt.Sprintf("%d", len(properties)+len(data)))
+	}
 
 	// Now we can encode the headers.
-	n.Headers.Encode(encoder)
+	headers.Encode(encoder)
 
 	// Write the properties as an opaque binary blob, followed by a trailing \n
 	encoder.Write(properties)
 
 	// Finally we can write the raw data.
-	if len(n.data) > 0 {
-		encoder.Write(n.data)
+	if len(data) > 0 {
+		encoder.Write(data)
 	}
 
 	encoder.Newlines(n.newlines)
+
+	return nil
 }
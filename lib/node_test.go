@@ -0,0 +1,92 @@
+package svn
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// encodeAndLoad encodes the repository and loads the dump it produced.
+func encodeAndLoad(t *testing.T, repos *Repos, deltas bool) *Repos {
+	t.Helper()
+	var out bytes.Buffer
+	encoder := NewEncoder(&out)
+	if deltas {
+		encoder.EnableDeltas()
+	}
+	for progress := range repos.Encode(encoder, 0, repos.GetHead()) {
+		if progress.Err != nil {
+			t.Fatal(progress.Err)
+		}
+	}
+	encoder.Close()
+
+	dumpfile, err := NewDumpStream("encoded", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpfile.LoadRevisions(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewRepos()
+	if err := loaded.AddDumpFile(dumpfile); err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestEncodeInheritedProperties(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		modify func(rev *Revision)
+		path   string
+		want   string
+	}{
+		{"copy given a property", func(rev *Revision) {
+			rev.AddCopyNode("copy", NodeKindFile, "file", 1).Properties.Set("b", []byte("2"))
+		}, "copy", "K 1\na\nV 1\n1\nK 1\nb\nV 1\n2\nPROPS-END\n"},
+		{"copy given a deletion", func(rev *Revision) {
+			rev.AddCopyNode("copy", NodeKindFile, "file", 1).Properties.Delete("a")
+		}, "copy", "PROPS-END\n"},
+		{"change given a property", func(rev *Revision) {
+			rev.Nodes = append(rev.Nodes, CreateNode(rev, NodeActionChange, NodeKindFile, "file"))
+			rev.Nodes[0].Properties.Set("a", []byte("3"))
+			rev.Nodes[0].Properties.Set("b", []byte("2"))
+		}, "file", "K 1\na\nV 1\n3\nK 1\nb\nV 1\n2\nPROPS-END\n"},
+		{"change of a copy", func(rev *Revision) {
+			rev.AddCopyNode("copy", NodeKindFile, "file", 1)
+			rev.Nodes = append(rev.Nodes, CreateNode(rev, NodeActionChange, NodeKindFile, "copy"))
+			rev.Nodes[1].Properties.Set("b", []byte("2"))
+		}, "copy", "K 1\na\nV 1\n1\nK 1\nb\nV 1\n2\nPROPS-END\n"},
+	} {
+		for _, deltas := range []bool{false, true} {
+			name := test.name
+			if deltas {
+				name += " deltas"
+			}
+			t.Run(name, func(t *testing.T) {
+				repos := NewRepos()
+				repos.AppendRevision(CreateRevision(0, "", date, ""))
+				rev := CreateRevision(1, "author", date, "add")
+				rev.AddFileNode("file", []byte("text\n")).Properties.Set("a", []byte("1"))
+				repos.AppendRevision(rev)
+				rev = CreateRevision(2, "author", date, "modify")
+				test.modify(rev)
+				repos.AppendRevision(rev)
+
+				loaded := encodeAndLoad(t, repos, deltas)
+				content, props, err := loaded.Cat(test.path, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := string(props.Bytes()); got != test.want {
+					t.Errorf("got %q, expected %q", got, test.want)
+				}
+				if string(content) != "text\n" {
+					t.Errorf("got content %q", content)
+				}
+			})
+		}
+	}
+}
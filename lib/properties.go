@@ -69,6 +69,16 @@ func NewProperties(dump *DumpReader, propLength int) (props *Properties, err err
 	return props, nil
 }
 
+// newEmptyProperties returns a property block with nothing in it, which
+// unlike no block at all is written out as PROPS-END.
+func newEmptyProperties() *Properties {
+	return &Properties{
+		index: make([]string, 0),
+		table: make(map[string][]byte),
+		raw:   append([]byte(nil), propertiesSuffix...),
+	}
+}
+
 func (p *Properties) Load() (err error) {
 	if len(p.raw) == 0 {
 		return nil
@@ -116,10 +126,12 @@ func readSizedField(data []byte, prefixes ...byte) (prefix byte, field []byte, b
 	return data[0], field, body, nil
 }
 
+// Bytes returns the encoded property block, which is empty if there never
+// was one and nothing has been added.
 func (p *Properties) Bytes() (data []byte) {
 	data = p.raw
 
-	if p.modified {
+	if p.modified && (p.raw != nil || len(p.index) > 0) {
 		data = make([]byte, 0, len(p.raw))
 		for _, key := range p.index {
 			value, present := p.table[key]
@@ -164,15 +176,89 @@ func (p *Properties) Get(key string) (value []byte, present bool) {
 	return value, present
 }
 
+// Keys returns the property names in the order they'll be written,
+// including those recorded as deleted.
+func (p *Properties) Keys() []string {
+	return append([]string(nil), p.index...)
+}
+
+// Set assigns a property, adding it after the existing properties if it
+// wasn't present. A key recorded as deleted becomes an assignment in place.
 func (p *Properties) Set(key string, value []byte) {
-	_, present := p.table[key]
-	if !present {
-		panic("shouldn't be any case to set a property that isn't already present.")
+	if Index(p.index, key) == -1 {
+		p.index = append(p.index, key)
 	}
 	p.table[key] = value
 	p.modified = true
 }
 
+// Delete records an explicit deletion of the property, written as a 'D'
+// record, replacing any assignment of it. This is how a node's property
+// changes tell svnadmin load to remove a property it inherited.
+func (p *Properties) Delete(key string) {
+	if Index(p.index, key) == -1 {
+		p.index = append(p.index, key)
+	}
+	delete(p.table, key)
+	p.modified = true
+}
+
+// Deleted returns true if the property is recorded as deleted.
+func (p *Properties) Deleted(key string) bool {
+	_, present := p.table[key]
+	return !present && Index(p.index, key) != -1
+}
+
+// mergeProperties returns a new property table produced by applying the
+// assignments and deletions in delta to a copy of base, which may be nil.
+// The result is still a property block, even if the delta removed every key.
+func mergeProperties(base, delta *Properties) *Properties {
+	merged := newEmptyProperties()
+	merged.modified = true
+
+	if base != nil {
+		for _, key := range base.index {
+			if value, present := base.table[key]; present {
+				merged.index = append(merged.index, key)
+				merged.table[key] = value
+			}
+		}
+	}
+
+	for _, key := range delta.index {
+		value, present := delta.table[key]
+		if !present {
+			merged.Remove(key)
+			continue
+		}
+		if _, exists := merged.table[key]; !exists {
+			merged.index = append(merged.index, key)
+		}
+		merged.table[key] = value
+	}
+
+	return merged
+}
+
+// ReplaceInValues replaces every occurrence of old with new in the values of
+// the properties selected by include, or of all of them if include is nil.
+func (p *Properties) ReplaceInValues(old, new []byte, include func(key string) bool) {
+	for key, value := range p.table {
+		if include != nil && !include(key) {
+			continue
+		}
+		if newValue := bytes.ReplaceAll(value, old, new); !bytes.Equal(value, newValue) {
+			p.table[key] = newValue
+			p.modified = true
+		}
+	}
+}
+
+// ApplyReplacements replaces every occurrence of each key of replacements
+// with its value in all of the property values.
+//
+// Deprecated: map iteration order is random, so overlapping replacements are
+// applied unpredictably. Call ReplaceInValues for each replacement in order.
 func (p *Properties) ApplyReplacements(replacements map[string]string) {
 	for key, value := range p.table {
 		newValue := value
@@ -186,6 +272,8 @@ func (p *Properties) ApplyReplacements(replacements map[string]string) {
 	}
 }
 
+// Remove drops any assignment or deletion of the property from the table,
+// returning false if there was neither.
 func (p *Properties) Remove(key string) bool {
 	if idx := Index(p.index, key); idx != -1 {
 		p.index = append(p.index[:idx], p.index[idx+1:]...)
@@ -0,0 +1,42 @@
+package svn
+
+import (
+	"testing"
+)
+
+// testProperties parses a property block, as it would appear in a dump.
+func testProperties(t *testing.T, block string) *Properties {
+	t.Helper()
+	props := &Properties{index: make([]string, 0), table: make(map[string][]byte), raw: []byte(block)}
+	if err := props.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return props
+}
+
+func TestMergeProperties(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		base, delta string
+		want        string
+	}{
+		{"no base", "", "K 1\na\nV 1\n1\nPROPS-END\n", "K 1\na\nV 1\n1\nPROPS-END\n"},
+		{"add", "K 1\na\nV 1\n1\nPROPS-END\n", "K 1\nb\nV 1\n2\nPROPS-END\n", "K 1\na\nV 1\n1\nK 1\nb\nV 1\n2\nPROPS-END\n"},
+		{"change", "K 1\na\nV 1\n1\nK 1\nb\nV 1\n2\nPROPS-END\n", "K 1\na\nV 1\n3\nPROPS-END\n", "K 1\na\nV 1\n3\nK 1\nb\nV 1\n2\nPROPS-END\n"},
+		{"delete", "K 1\na\nV 1\n1\nK 1\nb\nV 1\n2\nPROPS-END\n", "D 1\na\nPROPS-END\n", "K 1\nb\nV 1\n2\nPROPS-END\n"},
+		{"delete all", "K 1\na\nV 1\n1\nPROPS-END\n", "D 1\na\nPROPS-END\n", "PROPS-END\n"},
+		{"delete missing", "", "D 1\na\nPROPS-END\n", "PROPS-END\n"},
+		{"empty delta", "K 1\na\nV 1\n1\nPROPS-END\n", "PROPS-END\n", "K 1\na\nV 1\n1\nPROPS-END\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var base *Properties
+			if test.base != "" {
+				base = testProperties(t, test.base)
+			}
+			merged := mergeProperties(base, testProperties(t, test.delta))
+			if got := string(merged.Bytes()); got != test.want {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package svn
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 // Repos represents the loaded model of a Subversion repository.
 
@@ -11,12 +14,19 @@ type Repos struct {
 	Revisions []*Revision // List of revisions in the repository.
 
 	DumpFiles []*DumpFile // A list of all the dump files we loaded.
+
+	history *history // Path history as loaded, used to resolve delta bases.
+
+	// Path history as the revisions were when first encoded, kept for later
+	// encodes of other ranges until revisions are inserted or appended.
+	encodeHistory *history
 }
 
 func NewRepos() *Repos {
 	return &Repos{
 		Revisions: make([]*Revision, 0),
 		DumpFiles: make([]*DumpFile, 0),
+		history:   newHistory(),
 	}
 }
 
@@ -56,21 +66,137 @@ func (r *Repos) AddDumpFile(dumpfile *DumpFile) (err error) {
 		r.UUID = dumpfile.UUID
 	}
 
+	for _, rev := range dumpfile.Revisions {
+		r.resolveDeltas(rev)
+
+		if dumpfile.VerifyChecksums && dumpfile.DumpFormat >= 3 {
+			if err = dumpfile.verifyChecksums(rev, true); err != nil {
+				return fmt.Errorf("r%d: %w", rev.Number, err)
+			}
+		}
+	}
+
 	r.Revisions = append(r.Revisions, dumpfile.Revisions...)
 	r.DumpFiles = append(r.DumpFiles, dumpfile)
 
 	return nil
 }
 
+// InsertRevisions inserts revisions so that the first becomes revision at,
+// renumbering them and every revision after them, and adjusting the
+// Node-copyfrom-rev of later nodes that refer to a moved revision. References
+// in properties such as svn:mergeinfo are not adjusted. The new revisions join
+// the dump file of the revision before them.
+func (r *Repos) InsertRevisions(at int, revisions ...*Revision) error {
+	if at < 1 || at > len(r.Revisions) {
+		return fmt.Errorf("cannot insert revisions at r%d of r0:%d", at, r.GetHead())
+	}
+	count := len(revisions)
+	if count == 0 {
+		return nil
+	}
+
+	for _, rev := range r.Revisions[at:] {
+		rev.renumber(rev.Number + count)
+		for _, node := range rev.Nodes {
+			if fromRev, _, ok := node.Branched(); ok && fromRev >= at {
+				node.Headers.Set(NodeCopyfromRevHeader, strconv.Itoa(fromRev+count))
+			}
+		}
+	}
+	for idx, rev := range revisions {
+		rev.renumber(at + idx)
+	}
+
+	r.Revisions = append(r.Revisions[:at], append(append([]*Revision(nil), revisions...), r.Revisions[at:]...)...)
+
+	// Keep each dump file's revisions contiguous.
+	for _, dumpfile := range r.DumpFiles {
+		if pos := Index(dumpfile.Revisions, r.Revisions[at-1]); pos != -1 {
+			pos++
+			dumpfile.Revisions = append(dumpfile.Revisions[:pos], append(append([]*Revision(nil), revisions...), dumpfile.Revisions[pos:]...)...)
+			break
+		}
+	}
+
+	// History is ordered by revision number.
+	r.history = newRevisionsHistory(r.Revisions)
+	r.encodeHistory = nil
+
+	return nil
+}
+
+// AppendRevision adds a revision after the current head, numbering it to suit.
+func (r *Repos) AppendRevision(rev *Revision) {
+	rev.renumber(len(r.Revisions))
+	r.Revisions = append(r.Revisions, rev)
+	r.history.addRevision(rev)
+	r.encodeHistory = nil
+	if len(r.DumpFiles) > 0 {
+		last := r.DumpFiles[len(r.DumpFiles)-1]
+		last.Revisions = append(last.Revisions, rev)
+	}
+}
+
+// resolveDeltas records the revision's nodes in the path history, and ties any
+// delta-encoded text to the node it is relative to. Property deltas are expanded
+// into full property tables immediately since they are cheap.
+func (r *Repos) resolveDeltas(rev *Revision) {
+	for idx, node := range rev.Nodes {
+		entry := newHistoryEntry(node, nodeSeq(rev.Number, idx))
+
+		if node.IsDelta() {
+			base, _ := r.history.base(entry, node.Path())
+			if node.textDelta {
+				node.setDeltaBase(base.text)
+			}
+			if node.Headers.table[PropDeltaHeader] == "true" {
+				var baseProps *Properties
+				if base.props != nil {
+					baseProps = base.props.Properties
+				}
+				node.expandPropDelta(baseProps)
+			}
+		}
+
+		r.history.add(entry)
+	}
+}
+
+// EncodeDumpHeader writes the header blocks that begin every dump file.
+func EncodeDumpHeader(encoder *Encoder, dumpFormat int, uuid string) {
+	// We currently guarantee there are only these two headers.
+	encoder.Fprintf("%s: %d\n\n%s: %s\n\n", VersionStringHeader, dumpFormat, UUIDHeader, uuid)
+}
+
+// EncodingProgress reports each revision as Repos.Encode starts on it, or the
+// error that stopped the encoding, after which nothing more is sent.
 type EncodingProgress struct {
 	Revision int
 	Percent  float64
+	Err      error
 }
 
 func (r *Repos) Encode(encoder *Encoder, start, end int) <-chan EncodingProgress {
-	// Encode the header.
-	// We currently guarantee there are only these two headers.
-	encoder.Fprintf("%s: %d\n\n%s: %s\n\n", VersionStringHeader, r.DumpFormat, UUIDHeader, r.UUID)
+	// Delta encoding, and nodes that were given properties without a property
+	// block, need to know each node's predecessor as things stand now, after
+	// any changes that were made to the loaded revisions. Later revisions can't
+	// be predecessors, so one history of them all serves every range.
+	if r.encodeHistory == nil {
+		r.encodeHistory = newRevisionsHistory(r.Revisions)
+	}
+	encoder.history = r.encodeHistory
+
+	dumpFormat := r.DumpFormat
+	if dumpFormat == 0 {
+		// Built from scratch rather than loaded.
+		dumpFormat = 2
+	}
+	if encoder.deltas {
+		dumpFormat = 3
+	}
+
+	EncodeDumpHeader(encoder, dumpFormat, r.UUID)
 
 	ch := make(chan EncodingProgress, 4)
 
@@ -80,8 +206,11 @@ func (r *Repos) Encode(encoder *Encoder, start, end int) <-chan EncodingProgress
 		total := float64(r.GetHead() + 1)
 
 		for i := start; i <= end; i++ {
-			ch <- EncodingProgress{i, float64(i) * 100.0 / total}
-			r.Revisions[i].Encode(encoder)
+			ch <- EncodingProgress{Revision: i, Percent: float64(i) * 100.0 / total}
+			if err := r.Revisions[i].Encode(encoder); err != nil {
+				ch <- EncodingProgress{Revision: i, Err: err}
+				return
+			}
 		}
 	}()
 
@@ -0,0 +1,96 @@
+package svn
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testRepos returns a repository of r0:3 where r1 adds a file, r2 copies it
+// from r1 and r3 copies it from r2 and the root from r0.
+func testRepos() *Repos {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	repos := NewRepos()
+	repos.AppendRevision(CreateRevision(0, "", date, "r0"))
+
+	rev := CreateRevision(1, "author", date, "r1")
+	rev.AddFileNode("a", []byte("a"))
+	repos.AppendRevision(rev)
+
+	rev = CreateRevision(2, "author", date, "r2")
+	rev.AddCopyNode("b", NodeKindFile, "a", 1)
+	repos.AppendRevision(rev)
+
+	rev = CreateRevision(3, "author", date, "r3")
+	rev.AddCopyNode("c", NodeKindFile, "b", 2)
+	repos.AppendRevision(rev)
+
+	return repos
+}
+
+func TestInsertRevisions(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, test := range []struct {
+		at, count int
+		logs      []string // Log message of each revision afterwards.
+		copies    []string // Copy sources of the nodes, as path@rev.
+	}{
+		{1, 1, []string{"r0", "new0", "r1", "r2", "r3"}, []string{"a@2", "b@3"}},
+		{2, 2, []string{"r0", "r1", "new0", "new1", "r2", "r3"}, []string{"a@1", "b@4"}},
+		{3, 1, []string{"r0", "r1", "r2", "new0", "r3"}, []string{"a@1", "b@2"}},
+		{4, 2, []string{"r0", "r1", "r2", "r3", "new0", "new1"}, []string{"a@1", "b@2"}},
+		{2, 0, []string{"r0", "r1", "r2", "r3"}, []string{"a@1", "b@2"}},
+	} {
+		t.Run(strconv.Itoa(test.count)+"@"+strconv.Itoa(test.at), func(t *testing.T) {
+			// Load it from a dump so that there's a dump file to insert into.
+			repos := encodeAndLoad(t, testRepos(), false)
+			var revisions []*Revision
+			for i := 0; i < test.count; i++ {
+				// Numbered wrongly on purpose.
+				revisions = append(revisions, CreateRevision(99, "author", date, "new"+strconv.Itoa(i)))
+			}
+			if err := repos.InsertRevisions(test.at, revisions...); err != nil {
+				t.Fatal(err)
+			}
+
+			var logs, copies []string
+			for number, rev := range repos.Revisions {
+				if rev.Number != number {
+					t.Errorf("revision %d is numbered %d", number, rev.Number)
+				}
+				if header, _ := rev.Headers.Int(RevisionNumberHeader); header != number {
+					t.Errorf("revision %d has a Revision-number of %d", number, header)
+				}
+				log, _ := rev.Properties.Get(LogProperty)
+				logs = append(logs, string(log))
+				for _, node := range rev.Nodes {
+					if fromRev, fromPath, ok := node.Branched(); ok {
+						copies = append(copies, fromPath+"@"+strconv.Itoa(fromRev))
+					}
+				}
+			}
+			if !reflect.DeepEqual(logs, test.logs) {
+				t.Errorf("got revisions %q, expected %q", logs, test.logs)
+			}
+			if !reflect.DeepEqual(copies, test.copies) {
+				t.Errorf("got copies from %q, expected %q", copies, test.copies)
+			}
+			if !reflect.DeepEqual(repos.DumpFiles[0].Revisions, repos.Revisions) {
+				t.Error("the dump file's revisions don't match the repository's")
+			}
+
+			// The history follows the renumbered copies.
+			if content, _, err := repos.Cat("c", repos.GetHead()); err != nil || string(content) != "a" {
+				t.Errorf("got %q, %v for c", content, err)
+			}
+		})
+	}
+
+	repos := testRepos()
+	for _, at := range []int{-1, 0, 5} {
+		if err := repos.InsertRevisions(at, CreateRevision(99, "", date, "")); err == nil {
+			t.Errorf("inserting at r%d should have failed", at)
+		}
+	}
+}
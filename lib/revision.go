@@ -2,6 +2,8 @@ package svn
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 )
 
 type Revision struct {
@@ -12,6 +14,7 @@ type Revision struct {
 
 	dump        *DumpReader // The dump file this revision is from.
 	startOffset int         // Offset of first byte of this rev in that dump.
+	propsOffset int         // Offset of the revision's property block.
 	endOffset   int         // Offset of last byte of this rev in that dump.
 }
 
@@ -30,6 +33,8 @@ func NewRevision(dump *DumpReader) (rev *Revision, err error) {
 		return nil, fmt.Errorf("revision header: %w", err)
 	}
 
+	rev.propsOffset = dump.Tell()
+
 	// Find the length of the property data.
 	var propLen int
 	propLen, err = rev.Headers.Int(PropContentLengthHeader)
@@ -57,10 +62,43 @@ func NewRevision(dump *DumpReader) (rev *Revision, err error) {
 	return rev, nil
 }
 
+// CreateRevision returns a revision that wasn't read from a dump, with the
+// given author, date and log message properties and no nodes. An empty author
+// is left out, as svn does for anonymous commits.
+func CreateRevision(number int, author string, date time.Time, message string) *Revision {
+	rev := &Revision{
+		Number:     number,
+		Headers:    newHeaders(1),
+		Properties: newEmptyProperties(),
+	}
+
+	if author != "" {
+		rev.Properties.Set(AuthorProperty, []byte(author))
+	}
+	rev.Properties.Set(DateProperty, []byte(date.UTC().Format(DateLayout)))
+	rev.Properties.Set(LogProperty, []byte(message))
+
+	length := strconv.Itoa(len(rev.Properties.Bytes()))
+	rev.Headers.Set(RevisionNumberHeader, strconv.Itoa(number))
+	rev.Headers.Set(PropContentLengthHeader, length)
+	rev.Headers.Set(ContentLengthHeader, length)
+
+	return rev
+}
+
 func (r *Revision) Close() error {
+	if r.dump == nil {
+		return nil
+	}
 	return r.dump.Close()
 }
 
+// renumber changes the revision's number.
+func (r *Revision) renumber(number int) {
+	r.Number = number
+	r.Headers.Set(RevisionNumberHeader, strconv.Itoa(number))
+}
+
 // Load the nodes associated with this revision.
 func (r *Revision) Load() (err error) {
 	// Optimistically allocate a large block to reduce the number of reallocs.
@@ -107,13 +145,21 @@ func (r *Revision) GetNodeIndexesWithPrefix(prefix string) []int {
 	return nodes
 }
 
-func (r *Revision) Encode(encoder *Encoder) {
+// Encode writes the revision and its nodes to the encoder, stopping at the
+// first node that can't be encoded.
+func (r *Revision) Encode(encoder *Encoder) error {
 	// Encode the headers ready for writing in binary form.
 	properties := r.Properties.Bytes()
 
-	// Update the length headers, incase the length changed.
-	r.Headers.Set(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
-	r.Headers.Set(ContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+	// Update the length headers, incase the length changed or the revision
+	// gained properties.
+	if len(properties) > 0 {
+		r.Headers.Set(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+		r.Headers.Set(ContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+	} else {
+		r.Headers.update(PropContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+		r.Headers.update(ContentLengthHeader, fmt.Sprintf("%d", len(properties)))
+	}
 
 	// Encode the headers.
 	r.Headers.Encode(encoder)
@@ -123,6 +169,10 @@ func (r *Revision) Encode(encoder *Encoder) {
 	encoder.Write([]byte{'\n'})
 
 	for _, node := range r.Nodes {
-		node.Encode(encoder)
+		if err := node.Encode(encoder); err != nil {
+			return fmt.Errorf("r%d: %w", r.Number, err)
+		}
 	}
+
+	return nil
 }
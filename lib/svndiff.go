@@ -0,0 +1,361 @@
+package svn
+
+// svndiff.go implements the binary delta format Subversion uses for the
+// text of nodes in "--deltas" (format 3) dumps.
+//
+//	svndiff  := 'S' 'V' 'N' version window*
+//	window   := sview-offset sview-len tview-len ins-len new-len instructions new-data
+//
+// Integers are big-endian base-128 with the high bit as a continuation flag.
+// Version 0 stores the instructions and new-data sections raw, version 1
+// zlib-compresses them and version 2 lz4-compresses them.
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+var svndiffMagic = []byte{'S', 'V', 'N'}
+
+// Instruction opcodes, stored in the top two bits of the instruction byte.
+const (
+	svndiffCopySource = 0
+	svndiffCopyTarget = 1
+	svndiffCopyNew    = 2
+)
+
+// ApplySvndiff decodes an svndiff stream and applies it to source, returning
+// the reconstructed target text.
+func ApplySvndiff(source, delta []byte) ([]byte, error) {
+	if len(delta) < 4 || !bytes.HasPrefix(delta, svndiffMagic) {
+		return nil, fmt.Errorf("%w: missing svndiff header", ErrInvalidDelta)
+	}
+	version := delta[3]
+	if version > 2 {
+		return nil, fmt.Errorf("%w: unsupported svndiff version %d", ErrInvalidDelta, version)
+	}
+
+	target := make([]byte, 0, len(source))
+	data := delta[4:]
+	lastViewStart, lastViewEnd := 0, 0
+	for window := 0; len(data) > 0; window++ {
+		var header [5]int
+		var err error
+		for i := range header {
+			if header[i], data, err = readVarint(data); err != nil {
+				return nil, fmt.Errorf("window %d: %w", window, err)
+			}
+		}
+		viewOffset, viewLen, targetLen, insLen, newLen := header[0], header[1], header[2], header[3], header[4]
+
+		// The values may be anything up to 2^63-1, so check each before adding.
+		if viewOffset > len(source) || viewLen > len(source)-viewOffset {
+			return nil, fmt.Errorf("%w: window %d: source view exceeds source length", ErrInvalidDelta, window)
+		}
+		if viewLen > 0 && (viewOffset < lastViewStart || viewOffset+viewLen < lastViewEnd) {
+			return nil, fmt.Errorf("%w: window %d: backwards-sliding source view", ErrInvalidDelta, window)
+		}
+		if viewLen > 0 {
+			lastViewStart, lastViewEnd = viewOffset, viewOffset+viewLen
+		}
+		if insLen > len(data) || newLen > len(data)-insLen {
+			return nil, fmt.Errorf("%w: window %d: truncated", ErrInvalidDelta, window)
+		}
+
+		instructions, newData := data[:insLen], data[insLen:insLen+newLen]
+		data = data[insLen+newLen:]
+		if version > 0 {
+			if instructions, err = decompressSvndiffSection(instructions, version); err != nil {
+				return nil, fmt.Errorf("window %d: instructions: %w", window, err)
+			}
+			if newData, err = decompressSvndiffSection(newData, version); err != nil {
+				return nil, fmt.Errorf("window %d: new data: %w", window, err)
+			}
+		}
+
+		view := source[viewOffset : viewOffset+viewLen]
+		if target, err = applySvndiffWindow(target, view, targetLen, instructions, newData); err != nil {
+			return nil, fmt.Errorf("window %d: %w", window, err)
+		}
+	}
+
+	return target, nil
+}
+
+// svndiffWellFormed reports whether delta is structurally a valid svndiff,
+// with every window's sections accounted for, without needing its source.
+func svndiffWellFormed(delta []byte) bool {
+	if len(delta) < 4 || !bytes.HasPrefix(delta, svndiffMagic) || delta[3] > 2 {
+		return false
+	}
+	for data := delta[4:]; len(data) > 0; {
+		var header [5]int
+		var err error
+		for i := range header {
+			if header[i], data, err = readVarint(data); err != nil {
+				return false
+			}
+		}
+		sections := header[3] + header[4]
+		if sections > len(data) {
+			return false
+		}
+		data = data[sections:]
+	}
+	return true
+}
+
+// applySvndiffWindow appends targetLen bytes produced by the window's
+// instructions to target.
+func applySvndiffWindow(target, view []byte, targetLen int, instructions, newData []byte) ([]byte, error) {
+	start := len(target)
+	newOffset := 0
+	for len(instructions) > 0 {
+		op, length := int(instructions[0]>>6), int(instructions[0]&0x3f)
+		instructions = instructions[1:]
+
+		var err error
+		if length == 0 {
+			if length, instructions, err = readVarint(instructions); err != nil {
+				return nil, err
+			}
+		}
+		offset := 0
+		if op != svndiffCopyNew {
+			if offset, instructions, err = readVarint(instructions); err != nil {
+				return nil, err
+			}
+		}
+
+		if length > targetLen-(len(target)-start) {
+			return nil, fmt.Errorf("%w: instructions overrun the window's target length", ErrInvalidDelta)
+		}
+
+		switch op {
+		case svndiffCopySource:
+			if offset > len(view) || length > len(view)-offset {
+				return nil, fmt.Errorf("%w: source copy out of bounds", ErrInvalidDelta)
+			}
+			target = append(target, view[offset:offset+length]...)
+
+		case svndiffCopyTarget:
+			// The source region is allowed to overlap what we are writing,
+			// which is how runs get encoded, so copy a byte at a time.
+			if offset >= len(target)-start {
+				return nil, fmt.Errorf("%w: target copy out of bounds", ErrInvalidDelta)
+			}
+			for i := 0; i < length; i++ {
+				target = append(target, target[start+offset+i])
+			}
+
+		case svndiffCopyNew:
+			if length > len(newData)-newOffset {
+				return nil, fmt.Errorf("%w: new data copy out of bounds", ErrInvalidDelta)
+			}
+			target = append(target, newData[newOffset:newOffset+length]...)
+			newOffset += length
+
+		default:
+			return nil, fmt.Errorf("%w: invalid instruction opcode %d", ErrInvalidDelta, op)
+		}
+	}
+
+	if len(target)-start != targetLen {
+		return nil, fmt.Errorf("%w: window produced %d bytes, expected %d", ErrInvalidDelta, len(target)-start, targetLen)
+	}
+
+	return target, nil
+}
+
+// decompressSvndiffSection expands a version 1 (zlib) or 2 (lz4) section,
+// which is prefixed with its original length. Sections that would not have
+// benefited from compression are stored as-is.
+func decompressSvndiffSection(section []byte, version byte) ([]byte, error) {
+	length, body, err := readVarint(section)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == length {
+		return body, nil
+	}
+
+	var data []byte
+	if version == 1 {
+		reader, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDelta, err)
+		}
+	} else if data, err = lz4DecodeBlock(body, length); err != nil {
+		return nil, err
+	}
+
+	if len(data) != length {
+		return nil, fmt.Errorf("%w: section decompressed to %d bytes, expected %d", ErrInvalidDelta, len(data), length)
+	}
+
+	return data, nil
+}
+
+// readVarint decodes one of svndiff's variable length integers from the
+// front of data, returning the value and the remaining data.
+func readVarint(data []byte) (value int, rest []byte, err error) {
+	for i, b := range data {
+		if i >= 9 {
+			break
+		}
+		value = value<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			return value, data[i+1:], nil
+		}
+	}
+
+	return 0, nil, fmt.Errorf("%w: truncated or oversized integer", ErrInvalidDelta)
+}
+
+// svndiffWindowSize is the amount of target text per window, matching
+// Subversion's own SVN_DELTA_WINDOW_SIZE which readers enforce as a limit.
+const svndiffWindowSize = 100 * 1024
+
+// svndiffBlockSize is the length of the source blocks we index for matching.
+const svndiffBlockSize = 32
+
+// EncodeSvndiff returns a version 0 svndiff that transforms source into
+// target. Each window's source view is the same region of the source as the
+// region of the target it produces, so views only ever slide forwards.
+func EncodeSvndiff(source, target []byte) []byte {
+	delta := append([]byte{}, svndiffMagic...)
+	delta = append(delta, 0)
+
+	for offset := 0; offset < len(target); offset += svndiffWindowSize {
+		targetView := target[offset:minInt(offset+svndiffWindowSize, len(target))]
+
+		// Once the source is exhausted, windows have an empty view at 0.
+		viewOffset, sourceView := 0, []byte(nil)
+		if offset < len(source) {
+			viewOffset, sourceView = offset, source[offset:minInt(offset+svndiffWindowSize, len(source))]
+		}
+
+		instructions, newData := encodeSvndiffWindow(sourceView, targetView)
+
+		delta = appendVarint(delta, viewOffset)
+		delta = appendVarint(delta, len(sourceView))
+		delta = appendVarint(delta, len(targetView))
+		delta = appendVarint(delta, len(instructions))
+		delta = appendVarint(delta, len(newData))
+		delta = append(delta, instructions...)
+		delta = append(delta, newData...)
+	}
+
+	return delta
+}
+
+// encodeSvndiffWindow finds runs of the target that can be copied from the
+// source view, using a rolling hash over the target to look up blocks of the
+// source, and falls back to new data for everything else.
+func encodeSvndiffWindow(view, target []byte) (instructions, newData []byte) {
+	pending := 0
+	emitNew := func(end int) {
+		if end > pending {
+			instructions = appendSvndiffInstruction(instructions, svndiffCopyNew, end-pending, 0)
+			newData = append(newData, target[pending:end]...)
+		}
+	}
+
+	if len(view) >= svndiffBlockSize && len(target) >= svndiffBlockSize {
+		blocks := make(map[uint32]int, len(view)/svndiffBlockSize)
+		for i := len(view) - svndiffBlockSize; i >= 0; i -= svndiffBlockSize {
+			blocks[rollingHash(view[i:i+svndiffBlockSize])] = i
+		}
+
+		hash := rollingHash(target[:svndiffBlockSize])
+		for pos := 0; pos+svndiffBlockSize <= len(target); {
+			from, found := blocks[hash]
+			if found && bytes.Equal(view[from:from+svndiffBlockSize], target[pos:pos+svndiffBlockSize]) {
+				// Extend the match in both directions.
+				start, length := pos, svndiffBlockSize
+				for start > pending && from > 0 && target[start-1] == view[from-1] {
+					start, from, length = start-1, from-1, length+1
+				}
+				for start+length < len(target) && from+length < len(view) && target[start+length] == view[from+length] {
+					length++
+				}
+
+				emitNew(start)
+				instructions = appendSvndiffInstruction(instructions, svndiffCopySource, length, from)
+				pending = start + length
+
+				pos = pending
+				if pos+svndiffBlockSize <= len(target) {
+					hash = rollingHash(target[pos : pos+svndiffBlockSize])
+				}
+				continue
+			}
+
+			if pos+svndiffBlockSize < len(target) {
+				hash = rollHash(hash, target[pos], target[pos+svndiffBlockSize])
+			}
+			pos++
+		}
+	}
+
+	emitNew(len(target))
+
+	return instructions, newData
+}
+
+func appendSvndiffInstruction(instructions []byte, op, length, offset int) []byte {
+	if length < 0x40 {
+		instructions = append(instructions, byte(op<<6|length))
+	} else {
+		instructions = append(instructions, byte(op<<6))
+		instructions = appendVarint(instructions, length)
+	}
+	if op != svndiffCopyNew {
+		instructions = appendVarint(instructions, offset)
+	}
+	return instructions
+}
+
+// appendVarint appends value in svndiff's big-endian base-128 form.
+func appendVarint(data []byte, value int) []byte {
+	return append(data, encodeVarint(value)...)
+}
+
+func encodeVarint(value int) []byte {
+	var buffer [10]byte
+	i := len(buffer) - 1
+	buffer[i] = byte(value & 0x7f)
+	for value >>= 7; value > 0; value >>= 7 {
+		i--
+		buffer[i] = byte(value&0x7f) | 0x80
+	}
+	return buffer[i:]
+}
+
+const rollingHashBase = 257
+
+// rollingHashPower is rollingHashBase^(svndiffBlockSize-1), the weight of the
+// byte leaving the window.
+var rollingHashPower = func() uint32 {
+	power := uint32(1)
+	for i := 1; i < svndiffBlockSize; i++ {
+		power *= rollingHashBase
+	}
+	return power
+}()
+
+func rollingHash(block []byte) (hash uint32) {
+	for _, b := range block {
+		hash = hash*rollingHashBase + uint32(b)
+	}
+	return hash
+}
+
+func rollHash(hash uint32, out, in byte) uint32 {
+	return (hash-uint32(out)*rollingHashPower)*rollingHashBase + uint32(in)
+}
@@ -0,0 +1,299 @@
+package svn
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// editText returns a copy of text with random insertions, deletions and
+// changes, each of up to span bytes.
+func editText(random *rand.Rand, text []byte, edits, span int) []byte {
+	edited := append([]byte(nil), text...)
+	for i := 0; i < edits && len(edited) > 0; i++ {
+		at, length := random.Intn(len(edited)), 1+random.Intn(span)
+		switch random.Intn(3) {
+		case 0:
+			insert := make([]byte, length)
+			random.Read(insert)
+			edited = append(edited[:at], append(insert, edited[at:]...)...)
+		case 1:
+			edited = append(edited[:at], edited[minInt(at+length, len(edited)):]...)
+		default:
+			random.Read(edited[at:minInt(at+length, len(edited))])
+		}
+	}
+	return edited
+}
+
+// textLike returns size bytes of repetitive, text-like data.
+func textLike(random *rand.Rand, size int) []byte {
+	words := []string{"the ", "svn ", "dump ", "node ", "path ", "revision\n", "text ", "delta "}
+	var text bytes.Buffer
+	for text.Len() < size {
+		text.WriteString(words[random.Intn(len(words))])
+	}
+	return text.Bytes()[:size]
+}
+
+// svndiffTestCases returns source and target pairs over a range of sizes,
+// including ones either side of the window size.
+func svndiffTestCases() []struct {
+	name           string
+	source, target []byte
+} {
+	random := rand.New(rand.NewSource(1))
+	type testCase = struct {
+		name           string
+		source, target []byte
+	}
+	cases := []testCase{
+		{"empty", nil, nil},
+		{"empty source", nil, []byte("new text\n")},
+		{"empty target", []byte("old text\n"), nil},
+		{"one byte", []byte("a"), []byte("b")},
+	}
+	for _, size := range []int{31, 32, 33, 1000, svndiffWindowSize - 1, svndiffWindowSize, svndiffWindowSize + 1, 3*svndiffWindowSize + 17} {
+		source := textLike(random, size)
+		unrelated := make([]byte, size)
+		random.Read(unrelated)
+		cases = append(cases,
+			testCase{"unchanged " + strconv.Itoa(size), source, source},
+			testCase{"edited " + strconv.Itoa(size), source, editText(random, source, 1+size/500, 40)},
+			testCase{"unrelated " + strconv.Itoa(size), source, unrelated},
+			testCase{"truncated " + strconv.Itoa(size), source, source[:size/2]},
+			testCase{"extended " + strconv.Itoa(size), source[:size/2], source},
+		)
+	}
+	return cases
+}
+
+func TestSvndiffRoundTrip(t *testing.T) {
+	for _, test := range svndiffTestCases() {
+		t.Run(test.name, func(t *testing.T) {
+			delta := EncodeSvndiff(test.source, test.target)
+			if !svndiffWellFormed(delta) {
+				t.Fatal("encoded delta isn't well formed")
+			}
+			for version := byte(0); version <= 2; version++ {
+				versioned := recompressSvndiff(t, delta, version)
+				target, err := ApplySvndiff(test.source, versioned)
+				if err != nil {
+					t.Fatalf("version %d: %s", version, err)
+				}
+				if !bytes.Equal(target, test.target) {
+					t.Fatalf("version %d: applying the delta didn't reproduce the target", version)
+				}
+			}
+		})
+	}
+}
+
+// recompressSvndiff rewrites a version 0 svndiff as the given version, the
+// way Subversion writes them: each section is prefixed with its length and
+// is compressed unless that wouldn't make it smaller.
+func recompressSvndiff(t *testing.T, delta []byte, version byte) []byte {
+	t.Helper()
+	if version == 0 {
+		return delta
+	}
+
+	compress := func(section []byte) []byte {
+		var compressed []byte
+		if version == 1 {
+			var buffer bytes.Buffer
+			writer := zlib.NewWriter(&buffer)
+			_, _ = writer.Write(section)
+			_ = writer.Close()
+			compressed = buffer.Bytes()
+		} else {
+			compressed = lz4CompressBlock(section)
+		}
+		if len(compressed) >= len(section) {
+			compressed = section
+		}
+		return append(encodeVarint(len(section)), compressed...)
+	}
+
+	out := append(append([]byte{}, svndiffMagic...), version)
+	for data := delta[4:]; len(data) > 0; {
+		var header [5]int
+		var err error
+		for i := range header {
+			if header[i], data, err = readVarint(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		instructions := compress(data[:header[3]])
+		newData := compress(data[header[3] : header[3]+header[4]])
+		data = data[header[3]+header[4]:]
+
+		for _, value := range []int{header[0], header[1], header[2], len(instructions), len(newData)} {
+			out = appendVarint(out, value)
+		}
+		out = append(append(out, instructions...), newData...)
+	}
+	return out
+}
+
+// lz4CompressBlock is a greedy lz4 block compressor, following the format's
+// rules that the last five bytes are literals and no match starts in the
+// last twelve.
+func lz4CompressBlock(src []byte) []byte {
+	var dst []byte
+	appendLength := func(length int) {
+		for ; length >= 255; length -= 255 {
+			dst = append(dst, 255)
+		}
+		dst = append(dst, byte(length))
+	}
+	appendSequence := func(literals []byte, offset, match int) {
+		token := byte(minInt(len(literals), 15)) << 4
+		if offset > 0 {
+			token |= byte(minInt(match-4, 15))
+		}
+		dst = append(dst, token)
+		if len(literals) >= 15 {
+			appendLength(len(literals) - 15)
+		}
+		dst = append(dst, literals...)
+		if offset > 0 {
+			dst = append(dst, byte(offset), byte(offset>>8))
+			if match-4 >= 15 {
+				appendLength(match - 4 - 15)
+			}
+		}
+	}
+
+	seen := make(map[uint32]int)
+	anchor := 0
+	for i := 0; i+12 < len(src); {
+		key := binary.LittleEndian.Uint32(src[i:])
+		if from, ok := seen[key]; ok && i-from <= 0xffff {
+			length := 4
+			for i+length < len(src)-5 && src[from+length] == src[i+length] {
+				length++
+			}
+			appendSequence(src[anchor:i], i-from, length)
+			i += length
+			anchor = i
+			continue
+		}
+		seen[key] = i
+		i++
+	}
+	appendSequence(src[anchor:], 0, 0)
+
+	return dst
+}
+
+func TestApplySvndiffCopyTarget(t *testing.T) {
+	// The encoder never copies from the target, but Subversion's does, and
+	// such copies may overlap the bytes they produce.
+	delta := []byte("SVN\x00" +
+		"\x00\x08\x0d\x07\x02" + // view 0+8, target 13, 7 instruction bytes, 2 new
+		"\x03\x00" + // copy 3 from source at 0: abc
+		"\x82" + // 2 new: XY
+		"\x46\x03" + // copy 6 from target at 3: XYXYXY
+		"\x02\x06" + // copy 2 from source at 6: gh
+		"XY")
+	target, err := ApplySvndiff([]byte("abcdefgh"), delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(target) != "abcXYXYXYXYgh" {
+		t.Errorf("got %q", target)
+	}
+}
+
+func TestApplySvndiffInvalid(t *testing.T) {
+	// The largest value a varint can hold, which overflows when added to.
+	huge := string(encodeVarint(1<<63 - 1))
+	for _, test := range []struct {
+		name  string
+		delta string
+	}{
+		{"no header", "XYZ\x00"},
+		{"version 3", "SVN\x03"},
+		{"truncated window", "SVN\x00\x00\x00\x05\x01"},
+		{"truncated sections", "SVN\x00\x00\x00\x02\x01\x02\x82"},
+		{"view beyond source", "SVN\x00\x00\x09\x01\x02\x00\x01\x00"},
+		{"short target", "SVN\x00\x00\x00\x03\x01\x02\x82XY"},
+		{"new data overrun", "SVN\x00\x00\x00\x03\x01\x02\x83XY"},
+		{"huge view", "SVN\x00" + huge + huge + "\x01\x01\x00\x81"},
+		{"huge view offset", "SVN\x00" + huge + "\x01\x01\x01\x00\x81"},
+		{"huge sections", "SVN\x00\x00\x00\x01" + huge + huge + "\x81X"},
+		{"huge new length", "SVN\x00\x00\x00\x01\x01" + huge + "\x81X"},
+		{"huge source copy", "SVN\x00\x00\x08\x01\x0a\x00\x01" + huge},
+		{"huge new data copy", "SVN\x00\x00\x00\x01\x0a\x01\x80" + huge + "X"},
+		{"huge target copy", "SVN\x00\x00\x00\x02\x0c\x01\x81\x40" + huge + "\x00X"},
+		{"huge compressed section", "SVN\x02\x00\x00\x01\x0b\x02" + huge + "\x10\x81\x01X"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ApplySvndiff([]byte("abcdefgh"), []byte(test.delta)); !errors.Is(err, ErrInvalidDelta) {
+				t.Errorf("got %v, expected ErrInvalidDelta", err)
+			}
+		})
+	}
+}
+
+func TestLz4DecodeBlock(t *testing.T) {
+	long := bytes.Repeat([]byte("0123456789"), 60)
+	for _, test := range []struct {
+		name  string
+		block []byte
+		want  string
+	}{
+		{"literals", []byte("\x50hello"), "hello"},
+		{"overlapping match", []byte("\x1fa\x01\x00\x05\x50aaaaa"), "a" + string(bytes.Repeat([]byte("a"), 24)) + "aaaaa"},
+		{"long literals", append([]byte{0xf0, 255, 255, 600 - 15 - 510}, long...), string(long)},
+		{"match", []byte("\x42abcd\x04\x00\x10x"), "abcdabcdabx"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := lz4DecodeBlock(test.block, len(test.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != test.want {
+				t.Errorf("got %q, expected %q", decoded, test.want)
+			}
+		})
+	}
+
+	for _, test := range []struct {
+		name  string
+		block string
+		size  int
+	}{
+		{"zero offset", "\x10a\x00\x00", 5},
+		{"offset before start", "\x10a\x02\x00", 5},
+		{"truncated offset", "\x10a\x01", 5},
+		{"literals overrun", "\x50abc", 5},
+		{"truncated length", "\xf0", 20},
+		{"wrong size", "\x30abc", 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := lz4DecodeBlock([]byte(test.block), test.size); !errors.Is(err, ErrInvalidDelta) {
+				t.Errorf("got %v, expected ErrInvalidDelta", err)
+			}
+		})
+	}
+}
+
+func TestLz4RoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for _, size := range []int{0, 1, 12, 13, 100, 4096, 70000, 300000} {
+		data := textLike(random, size)
+		decoded, err := lz4DecodeBlock(lz4CompressBlock(data), len(data))
+		if err != nil {
+			t.Fatalf("%d bytes: %s", size, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Fatalf("%d bytes: decoded data differs", size)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package svn
+
+// tree.go answers questions about what the repository looked like at a given
+// revision, by replaying the adds, deletes, replaces and copies that led up
+// to it.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tree is a versioned view of a repository's paths, built from a snapshot of
+// its revisions. Changes made to the revisions afterwards aren't reflected;
+// build a new Tree to see them.
+type Tree struct {
+	history   *history
+	revisions []*Revision
+	head      int
+}
+
+// PathInfo describes a path as it existed at the end of a revision.
+type PathInfo struct {
+	Path     string   // Path asked about, without leading or trailing slashes.
+	Revision int      // Revision asked about.
+	Kind     NodeKind // File or directory.
+
+	state   pathState
+	history *history
+}
+
+// NewTree builds a tree from revisions, which must be consecutive from r0.
+func NewTree(revisions []*Revision) *Tree {
+	return &Tree{
+		history:   newRevisionsHistory(revisions),
+		revisions: revisions,
+		head:      len(revisions) - 1,
+	}
+}
+
+// Tree builds a tree of the repository's revisions as they are now.
+func (r *Repos) Tree() *Tree {
+	return NewTree(r.Revisions)
+}
+
+// Head returns the last revision in the tree.
+func (t *Tree) Head() int {
+	return t.head
+}
+
+// Stat returns what was at path at the end of revision rev, or false if
+// nothing was. The root always exists, as a directory.
+func (t *Tree) Stat(path string, rev int) (*PathInfo, bool) {
+	if rev < 0 || rev > t.head {
+		return nil, false
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return &PathInfo{Path: path, Revision: rev, Kind: NodeKindDir}, true
+	}
+
+	state, exists := t.history.lookup(path, revisionBound(rev))
+	if !exists {
+		return nil, false
+	}
+
+	return &PathInfo{Path: path, Revision: rev, Kind: state.kind, state: state, history: t.history}, true
+}
+
+// Exists returns true if there was something at path at the end of rev.
+func (t *Tree) Exists(path string, rev int) bool {
+	_, exists := t.Stat(path, rev)
+	return exists
+}
+
+// List returns the sorted names of the entries in the directory at path at
+// the end of rev, or nil if it wasn't a directory.
+func (t *Tree) List(path string, rev int) []string {
+	if rev < 0 || rev > t.head {
+		return nil
+	}
+	return t.history.list(path, revisionBound(rev))
+}
+
+// Properties returns the path's properties. Paths that have never had a
+// property block, such as the root, have an empty table.
+func (p *PathInfo) Properties() *Properties {
+	if p.state.props == nil {
+		return &Properties{index: make([]string, 0), table: make(map[string][]byte)}
+	}
+	return p.history.properties(p.state.props)
+}
+
+// Node returns the node that last provided the path's text, which may be
+// from a different path it was copied from, or nil if it has no text.
+func (p *PathInfo) Node() *Node {
+	return p.state.text
+}
+
+// Content returns the path's full text, following copies back to the node
+// that last carried a text block. Files without one are empty, and
+// directories have no content.
+func (p *PathInfo) Content() ([]byte, error) {
+	if p.Kind != NodeKindFile {
+		return nil, fmt.Errorf("%w: %s@%d", ErrNotAFile, p.Path, p.Revision)
+	}
+	if p.state.text == nil {
+		return []byte{}, nil
+	}
+	return p.state.text.Content()
+}
+
+// Cat returns the full text and properties of the file at path as of the end
+// of revision rev.
+func (t *Tree) Cat(path string, rev int) ([]byte, *Properties, error) {
+	info, exists := t.Stat(path, rev)
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s@%d", ErrPathNotFound, strings.Trim(path, "/"), rev)
+	}
+	content, err := info.Content()
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, info.Properties(), nil
+}
+
+// Cat returns the full text and properties of the file at path as of the end
+// of revision rev. It builds a Tree of the repository each time, so use
+// Tree.Cat when retrieving many files.
+func (r *Repos) Cat(path string, rev int) ([]byte, *Properties, error) {
+	return r.Tree().Cat(path, rev)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// logSeparator divides log entries, as in `svn log`.
+var logSeparator = strings.Repeat("-", 72)
+
+// logActions are the letters `svn log -v` uses for each node action.
+var logActions = map[svn.NodeAction]string{
+	svn.NodeActionAdd:     "A",
+	svn.NodeActionDelete:  "D",
+	svn.NodeActionChange:  "M",
+	svn.NodeActionReplace: "R",
+}
+
+// showLog prints the history of the -log path in the style of `svn log -v`,
+// limited to the -revs range if one was given.
+func showLog(status *Status) error {
+	fromRev, toRev := 0, status.GetHead()
+	if *revisionRange != "" {
+		fromRev, toRev = rangeFirst, rangeLast
+	}
+
+	entries, err := status.Log(*logPath, fromRev, toRev)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		lines := strings.Count(entry.Message, "\n") + 1
+		if entry.Message == "" {
+			lines = 0
+		}
+		date := ""
+		if !entry.Date.IsZero() {
+			date = entry.Date.Format("2006-01-02 15:04:05 -0700 (Mon, 02 Jan 2006)")
+		}
+		author := entry.Author
+		if author == "" {
+			author = "(no author)"
+		}
+
+		fmt.Fprintln(console, logSeparator)
+		fmt.Fprintf(console, "r%d | %s | %s | %d line%s\n", entry.Revision, author, date, lines, plural(lines))
+		fmt.Fprintln(console, "Changed paths:")
+		for _, node := range entry.Changes {
+			change := fmt.Sprintf("   %s /%s", logActions[node.Action], strings.Trim(node.Path(), "/"))
+			if fromRev, fromPath, ok := node.Branched(); ok {
+				change += fmt.Sprintf(" (from /%s:%d)", strings.Trim(fromPath, "/"), fromRev)
+			}
+			fmt.Fprintln(console, change)
+		}
+		fmt.Fprintf(console, "\n%s\n", entry.Message)
+	}
+	fmt.Fprintln(console, logSeparator)
+
+	return nil
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -35,15 +35,24 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	svn "github.com/kfsone/svn-go/lib"
 )
 
+// stdStream is the filename used to denote stdin/stdout.
+const stdStream = "-"
+
+// console is where messages are written; stderr if stdout is carrying a dump.
+var console io.Writer = os.Stdout
+
 type IterDirection int
 
 const (
@@ -58,6 +67,9 @@ type Status struct {
 	folderAdds map[string]*svn.Node
 	branchNews map[string]*svn.Node
 	branchAdds map[string]*svn.Node
+
+	// Revisions by each svn:author the 'authors' rules don't map.
+	unmappedAuthors map[string]int
 }
 
 func NewStatus() (status *Status, err error) {
@@ -71,6 +83,8 @@ func NewStatus() (status *Status, err error) {
 		branchNews: make(map[string]*svn.Node),
 		// The LAST creation of every branch.
 		branchAdds: make(map[string]*svn.Node),
+
+		unmappedAuthors: make(map[string]int),
 	}
 
 	if status.rules, err = NewRules(*rulesFile); err != nil {
@@ -84,7 +98,7 @@ func main() {
 	parseCommandLine()
 
 	if err := run(); err != nil {
-		fmt.Println(fmt.Errorf("error: %w", err))
+		fmt.Fprintln(console, fmt.Errorf("error: %w", err))
 		os.Exit(1)
 	}
 }
@@ -94,7 +108,7 @@ func Log(format string, args ...any) {
 		s := fmt.Sprintf("-- "+format, args...)
 		s = strings.ReplaceAll(s, "\r", "<cr>")
 		s = strings.ReplaceAll(s, "\n", "<lf>")
-		fmt.Println(s)
+		fmt.Fprintln(console, s)
 	}
 }
 
@@ -104,18 +118,21 @@ func Info(format string, args ...interface{}) {
 		s := fmt.Sprintf("-- "+format, args...)
 		s = strings.ReplaceAll(s, "\r", "<cr>")
 		s = strings.ReplaceAll(s, "\n", "<lf>")
-		fmt.Println(s)
+		fmt.Fprintln(console, s)
 	}
 }
 
 func run() error {
 	// Determine what files we're going to read.
-	filenames, err := filepath.Glob(*dumpFileName)
-	if err != nil {
-		return fmt.Errorf("invalid dump file/glob: %s: %w", *dumpFileName, err)
-	}
-	if len(filenames) == 0 {
-		return fmt.Errorf("no matching dump files found: %s", *dumpFileName)
+	filenames := []string{stdStream}
+	if *dumpFileName != stdStream {
+		var err error
+		if filenames, err = filepath.Glob(*dumpFileName); err != nil {
+			return fmt.Errorf("invalid dump file/glob: %s: %w", *dumpFileName, err)
+		}
+		if len(filenames) == 0 {
+			return fmt.Errorf("no matching dump files found: %s", *dumpFileName)
+		}
 	}
 
 	// Prepare a repository view to load dumps into.
@@ -124,44 +141,86 @@ func run() error {
 		return err
 	}
 
+	if *revisionRange != "" && *logPath == "" {
+		return extractRange(filenames)
+	}
+
+	if *repairCRLF {
+		return repairDumps(filenames)
+	}
+
 	Info("Loading %d dump files", len(filenames))
-	for _, filename := range filenames {
-		Log("Loading dump file: %s", filename)
-		dumpfile, err := svn.NewDumpFile(filename)
-		if err != nil {
-			return err
-		}
-		if err := dumpfile.LoadRevisions(); err != nil {
-			return err
-		}
+	dumpfiles, err := loadDumpFiles(filenames)
+	if errors.Is(err, svn.ErrWindowsDumpFile) {
+		return fmt.Errorf("%w (use -repair-crlf to write a corrected copy)", err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, dumpfile := range dumpfiles {
 		if err := status.AddDumpFile(dumpfile); err != nil {
-			return err
+			return fmt.Errorf("%s: %w", dumpfile.Filename, err)
 		}
 	}
 
+	if err := reportMismatches(status.DumpFiles); err != nil {
+		return err
+	}
+
 	if *pathInfo {
-		dumpPathInfo(status)
+		dumpPathInfo(status.Revisions)
+	}
+
+	if *verifyRoundtrip {
+		return verifyRoundTrip(status)
 	}
 
 	Info("Normalizing %d revisions", len(status.Revisions))
 	for _, rev := range status.Revisions {
 		processRevHelper(rev, status)
 	}
+	if err := reportUnmappedAuthors(status); err != nil {
+		return err
+	}
 
 	Info("Analyzing")
 	if err = analyze(status); err != nil {
 		return err
 	}
 
+	if *logPath != "" {
+		if err = showLog(status); err != nil {
+			return err
+		}
+	}
+
+	if *diffRange != "" {
+		if err = showDiff(status); err != nil {
+			return err
+		}
+	}
+
+	if *exportRevision != "" {
+		if err = export(status); err != nil {
+			return err
+		}
+	}
+
+	if *gitFilename != "" {
+		if err = writeGit(*gitFilename, status); err != nil {
+			return err
+		}
+	}
+
 	if *outFilename != "" {
 		err = singleDump(*outFilename, status, 0, status.GetHead())
 		if err == nil {
-			fmt.Println("100% Complete")
+			fmt.Fprintln(console, "100% Complete")
 		}
 	} else if *outDir != "" {
 		err = multiDump(*outDir, status)
 		if err == nil {
-			fmt.Println("100% Complete")
+			fmt.Fprintln(console, "100% Complete")
 		}
 	}
 
@@ -180,28 +239,167 @@ func must(err error) {
 	}
 }
 
-func singleDump(filename string, status *Status, start, end int) error {
-	Info("Dumping r%9d:%9d -> %s", start, end, filename)
+// openDumpFile maps the named dump file, or prepares to stream it from stdin.
+func openDumpFile(filename string) (*svn.DumpFile, error) {
+	if filename == stdStream {
+		return svn.NewDumpStream("stdin", os.Stdin)
+	}
+	return svn.NewDumpFile(filename)
+}
+
+// loadDumpFiles parses the given dump files concurrently, across up to -jobs
+// workers, and returns them ordered by their first revision ready to be added
+// to a repository.
+func loadDumpFiles(filenames []string) ([]*svn.DumpFile, error) {
+	workers := *jobs
+	if workers > len(filenames) {
+		workers = len(filenames)
+	}
 
-	out, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	dumpfiles := make([]*svn.DumpFile, len(filenames))
+	errs := make([]error, len(filenames))
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				dumpfiles[idx], errs[idx] = loadDumpFile(filenames[idx])
+			}
+		}()
+	}
+	for idx := range filenames {
+		work <- idx
+	}
+	close(work)
+	wg.Wait()
+
+	for idx, err := range errs {
+		if err != nil {
+			// Don't leave the files that did load mapped or open.
+			for _, dumpfile := range dumpfiles {
+				if dumpfile != nil {
+					_ = dumpfile.Close()
+				}
+			}
+			return nil, fmt.Errorf("%s: %w", filenames[idx], err)
+		}
+	}
+
+	// Globs needn't list files in revision order.
+	sort.SliceStable(dumpfiles, func(i, j int) bool {
+		return firstRevision(dumpfiles[i]) < firstRevision(dumpfiles[j])
+	})
+
+	return dumpfiles, nil
+}
+
+// loadDumpFile opens and parses a single dump file.
+func loadDumpFile(filename string) (*svn.DumpFile, error) {
+	Log("Loading dump file: %s", filename)
+	dumpfile, err := openDumpFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		must(out.Close())
-	}()
+	dumpfile.VerifyChecksums = *verifyChecksums
+	if err := dumpfile.LoadRevisions(); err != nil {
+		_ = dumpfile.Close()
+		return nil, err
+	}
+	if *useIndex {
+		updateIndex(dumpfile)
+	}
+	return dumpfile, nil
+}
+
+// firstRevision returns the number of the first revision in a dump file, or
+// -1 if it has none.
+func firstRevision(dumpfile *svn.DumpFile) int {
+	if len(dumpfile.Revisions) == 0 {
+		return -1
+	}
+	return dumpfile.Revisions[0].Number
+}
 
-	enc := svn.NewEncoder(out)
-	defer enc.Close()
+// reportMismatches lists any checksum mismatches found while loading, and
+// returns an error if there were any.
+func reportMismatches(dumpfiles []*svn.DumpFile) error {
+	mismatches := 0
+	for _, dumpfile := range dumpfiles {
+		for _, mismatch := range dumpfile.Mismatches {
+			fmt.Fprintf(console, "** %s: %s\n", dumpfile.Filename, mismatch)
+		}
+		mismatches += len(dumpfile.Mismatches)
+	}
 
-	for progress := range status.Encode(enc, start, end) {
-		fmt.Printf("%5.2f%% r%d\r", progress.Percent, progress.Revision)
+	if mismatches > 0 {
+		return fmt.Errorf("%d checksum mismatch(es) found", mismatches)
 	}
-	fmt.Printf("%6s %11s\r", "", "")
 
 	return nil
 }
 
+func singleDump(filename string, status *Status, start, end int) error {
+	Info("Dumping r%9d:%9d -> %s", start, end, filename)
+
+	return writeDump(filename, func(enc *svn.Encoder) error {
+		for progress := range status.Encode(enc, start, end) {
+			if progress.Err != nil {
+				return progress.Err
+			}
+			fmt.Fprintf(console, "%5.2f%% r%d\r", progress.Percent, progress.Revision)
+		}
+		fmt.Fprintf(console, "%6s %11s\r", "", "")
+		return nil
+	})
+}
+
+// writeDump creates the named file, or uses stdout for '-', compressing it
+// if the name asks for it, and has encode write the dump to it.
+func writeDump(filename string, encode func(enc *svn.Encoder) error) error {
+	return writeOutput(filename, func(writer io.Writer) error {
+		enc := svn.NewEncoder(writer)
+		defer enc.Close()
+		if *writeDeltas {
+			enc.EnableDeltas()
+		}
+
+		return encode(enc)
+	})
+}
+
+// writeOutput creates the named file, or uses stdout for '-', compressing it
+// if the name asks for it, and has write fill it.
+func writeOutput(filename string, write func(writer io.Writer) error) error {
+	out := os.Stdout
+	if filename != stdStream {
+		var err error
+		if out, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+			return err
+		}
+		defer func() {
+			must(out.Close())
+		}()
+	}
+
+	// Compress the output if the filename asks for it.
+	var writer io.Writer = out
+	if compression := svn.CompressionForFilename(filename); compression != nil {
+		compressor, err := compression.NewWriter(out)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", filename, compression.Name, err)
+		}
+		defer func() {
+			must(compressor.Close())
+		}()
+		writer = compressor
+	}
+
+	return write(writer)
+}
+
 func multiDump(outPath string, status *Status) error {
 	// MkdirAll does nothing if the path already exists as a directory.
 	if err := os.MkdirAll(outPath, 0700); err != nil {
@@ -214,13 +412,18 @@ func multiDump(outPath string, status *Status) error {
 		if err := singleDump(dumpFilename, status, start, end); err != nil {
 			return err
 		}
+	}
+
+	// Later files can be delta encoded against the text of earlier ones, so
+	// nothing is closed until every file has been written.
+	for _, dumpfile := range status.DumpFiles {
 		if err := dumpfile.Close(); err != nil {
 			return fmt.Errorf("closing dump files: %w", err)
 		}
 		if *removeOriginals {
 			Log("-> Removing original dump file: %s", dumpfile.Filename)
 			if err := os.Remove(dumpfile.Filename); err != nil {
-				fmt.Printf("** error removing original dump file: %s\n", err)
+				fmt.Fprintf(console, "** error removing original dump file: %s\n", err)
 			}
 		}
 	}
@@ -329,8 +532,8 @@ func getRefitBranches(status *Status) <-chan *svn.Node {
 		defer close(out)
 		for _, node := range status.branchAdds {
 			path := node.Path()
-			for _, prefix := range status.rules.RetroPaths {
-				if svn.MatchPathPrefix(path, prefix) {
+			for _, retrofit := range inRange(status.rules.RetroPaths, node.Revision) {
+				if prefix := retrofit.Path; svn.MatchPathPrefix(path, prefix) {
 					_, branchPath, _ := node.Branched()
 					if !svn.MatchPathPrefix(branchPath, prefix) {
 						out <- node
@@ -344,12 +547,12 @@ func getRefitBranches(status *Status) <-chan *svn.Node {
 	return out
 }
 
-func dumpPathInfo(status *Status) {
-	fmt.Printf("-- Path Info --\n")
+func dumpPathInfo(revisions []*svn.Revision) {
+	fmt.Fprintf(console, "-- Path Info --\n")
 	originals := make(map[string]int)
 	finals := make(map[string]int)
 
-	for _, rev := range status.Revisions {
+	for _, rev := range revisions {
 		for _, node := range rev.Nodes {
 			if node.Kind == svn.NodeKindDir && node.Action == svn.NodeActionAdd {
 				path := node.Path()
@@ -362,7 +565,7 @@ func dumpPathInfo(status *Status) {
 	}
 
 	if len(originals) == 0 {
-		fmt.Println("-- No directories created.")
+		fmt.Fprintln(console, "-- No directories created.")
 		return
 	}
 
@@ -386,6 +589,6 @@ func dumpPathInfo(status *Status) {
 			detail = fmt.Sprintf("r%d, ..., r%d", original, final)
 		}
 
-		fmt.Printf(format, path, detail)
+		fmt.Fprintf(console, format, path, detail)
 	}
 }
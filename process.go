@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
+	"strings"
 
 	svn "github.com/kfsone/svn-go/lib"
 )
@@ -36,28 +39,66 @@ func mapDirectoryCreations(rev *svn.Revision, status *Status) {
 // This even factors in the elimination of a top-level node, e.g. if you were
 // replacing /svn/repos -> /, then you would have bogus 'add' operations. It also
 // checks for out-of-bounds conditions like an attempt to delete such a directory,
-// since you just can't.
-func applyReplace(rev *svn.Revision, replacements map[string]string) {
+// since you just can't, and for rules that send two paths to the same place.
+// Copy sources are rewritten by the rules that applied at the revision they
+// were copied from, so that they still name the path as it was rewritten there.
+func applyReplace(rev *svn.Revision, status *Status) {
+	replacements := Replacements(inRange(status.rules.Replace, rev))
+
 	// Apply 'replace' rules to the revision header.
-	rev.Properties.ApplyReplacements(replacements)
+	for _, rule := range replacements {
+		if rule.pattern != nil {
+			// Regex and glob rules only rewrite paths.
+			continue
+		}
+		from, to := []byte(rule.From), []byte(rule.To)
+		if rule.Applies(ReplaceLog) {
+			rev.Properties.ReplaceInValues(from, to, func(key string) bool { return key == svn.LogProperty })
+		}
+		if rule.Applies(ReplaceProperties) {
+			rev.Properties.ReplaceInValues(from, to, func(key string) bool { return key != svn.LogProperty })
+		}
+	}
 
 	deadNodes := make([]*svn.Node, 0)
 
+	// The original path of each node's new path, and of each new copy source
+	// in the revision it was copied from.
+	sources := make(map[string]string)
+	copySources := make(map[int]map[string]string)
+
 	// And apply 'replace' rules to all of our revisions.
 	for _, node := range rev.Nodes {
 		// Fix the paths of every node in this revision.
 		path := node.Path()
+		original := path
 		changedPath := false
-		if changed := svn.ReplacePathPrefixes(path, replacements); changed != path {
+		if changed := replacePath(path, replacements, ReplacePaths); changed != path {
 			node.Headers.Set(svn.NodePathHeader, changed)
 			path = changed
 			changedPath = true
 		}
+		if source, seen := sources[path]; seen && source != original {
+			panic(fmt.Errorf("replace maps both %s and %s to %s at r%d", source, original, path, rev.Number))
+		}
+		sources[path] = original
 
-		if _, branchPath, branched := node.Branched(); branched {
-			if changed := svn.ReplacePathPrefixes(branchPath, replacements); changed != branchPath {
+		if branchRev, branchPath, branched := node.Branched(); branched {
+			sourceReplacements := replacements
+			if branchRev >= 0 && branchRev < len(status.Revisions) {
+				sourceReplacements = inRange(status.rules.Replace, status.Revisions[branchRev])
+			}
+			changed := replacePath(branchPath, sourceReplacements, ReplaceCopySources)
+			if changed != branchPath {
 				node.Headers.Set(svn.NodeCopyfromPathHeader, changed)
 			}
+			if copySources[branchRev] == nil {
+				copySources[branchRev] = make(map[string]string)
+			}
+			if source, seen := copySources[branchRev][changed]; seen && source != branchPath {
+				panic(fmt.Errorf("replace maps both %s@%d and %s@%d to %s at r%d", source, branchRev, branchPath, branchRev, changed, rev.Number))
+			}
+			copySources[branchRev][changed] = branchPath
 		}
 
 		// Did that bump us up to an invalid operation on root?
@@ -67,7 +108,18 @@ func applyReplace(rev *svn.Revision, replacements map[string]string) {
 			}
 		}
 
-		node.Properties.ApplyReplacements(replacements)
+		for _, rule := range replacements {
+			if !rule.Applies(ReplaceProperties) {
+				continue
+			}
+			if rule.pattern == nil {
+				node.Properties.ReplaceInValues([]byte(rule.From), []byte(rule.To), nil)
+			} else if mergeinfo, ok := node.Properties.Get(svn.MergeinfoProperty); ok {
+				if changed := rewriteMergeinfo(mergeinfo, &rule); !bytes.Equal(changed, mergeinfo) {
+					node.Properties.Set(svn.MergeinfoProperty, changed)
+				}
+			}
+		}
 	}
 
 	if len(deadNodes) > 0 {
@@ -83,6 +135,32 @@ func applyReplace(rev *svn.Revision, replacements map[string]string) {
 	}
 }
 
+// replacePath applies the replace rules for target to a path, in order.
+func replacePath(path string, replacements Replacements, target string) string {
+	for _, rule := range replacements {
+		if rule.Applies(target) {
+			path = rule.RewritePath(path)
+		}
+	}
+	return path
+}
+
+// rewriteMergeinfo applies a path rule to the paths in svn:mergeinfo, which
+// has a "/path:revisions" line for each merge source.
+func rewriteMergeinfo(mergeinfo []byte, rule *ReplaceRule) []byte {
+	lines := strings.Split(string(mergeinfo), "\n")
+	for i, line := range lines {
+		colon := strings.LastIndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+		if path := line[:colon]; strings.HasPrefix(path, "/") {
+			lines[i] = "/" + rule.RewritePath(strings.TrimPrefix(path, "/")) + line[colon:]
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
 // isChangedNodePathDefunct returns true if the node would now be defunct because it tries
 // to apply an impossible operation to the root directory such as add or delete.
 func isChangedNodePathDefunct(node *svn.Node) bool {
@@ -112,32 +190,106 @@ func isChangedNodePathDefunct(node *svn.Node) bool {
 // parsePropertiesWorker reads any properties in each revision and its nodes,
 // and expands them into a Properties object.
 func processRevHelper(rev *svn.Revision, status *Status) {
+	// Apply 'authors'.
+	applyAuthors(rev, status)
+
 	// Apply 'replace'.
-	applyReplace(rev, status.rules.Replace)
+	applyReplace(rev, status)
+
+	// Apply 'log-rewrite'.
+	applyLogRewrites(rev, inRange(status.rules.LogRewrite, rev))
 
 	// Find where all the directories are created.
 	mapDirectoryCreations(rev, status)
 
 	// Apply 'filter'.
-	applyFilter(rev, status.rules.Filter)
+	applyFilter(rev, status)
 
 	// Apply 'strip-props'.
-	applyStripProps(rev, status.rules.StripProps)
+	applyStripProps(rev, inRange(status.rules.StripProps, rev))
+}
+
+// applyAuthors renames the revision's svn:author as the 'authors' rules say,
+// counting the authors they neither map from nor map to.
+func applyAuthors(rev *svn.Revision, status *Status) {
+	if len(status.rules.Authors) == 0 && !*requireAuthors {
+		return
+	}
+	author, ok := rev.Properties.Get(svn.AuthorProperty)
+	if !ok {
+		return
+	}
+	if mapped, ok := status.rules.Authors[string(author)]; ok {
+		rev.Properties.Set(svn.AuthorProperty, []byte(mapped))
+	} else if !status.rules.mappedAuthors[string(author)] {
+		status.unmappedAuthors[string(author)]++
+	}
+}
+
+// reportUnmappedAuthors lists the authors the 'authors' rules didn't map, and
+// fails if -require-authors was given and there were any.
+func reportUnmappedAuthors(status *Status) error {
+	if len(status.unmappedAuthors) == 0 {
+		return nil
+	}
+
+	authors := make([]string, 0, len(status.unmappedAuthors))
+	for author := range status.unmappedAuthors {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	fmt.Fprintf(console, "** %d author%s not mapped by the authors rules:\n", len(authors), plural(len(authors)))
+	for _, author := range authors {
+		revisions := status.unmappedAuthors[author]
+		fmt.Fprintf(console, "   %s (%d revision%s)\n", author, revisions, plural(revisions))
+	}
+
+	if *requireAuthors {
+		return fmt.Errorf("%d unmapped author%s", len(authors), plural(len(authors)))
+	}
+	return nil
 }
 
-func applyFilter(rev *svn.Revision, filters []string) {
+// applyLogRewrites applies each 'log-rewrite' substitution, in order, to the
+// revision's svn:log.
+func applyLogRewrites(rev *svn.Revision, rewrites []LogRewrite) {
+	if len(rewrites) == 0 {
+		return
+	}
+	message, ok := rev.Properties.Get(svn.LogProperty)
+	if !ok {
+		return
+	}
+	rewritten := message
+	for _, rewrite := range rewrites {
+		rewritten = rewrite.matchRegexp.ReplaceAll(rewritten, []byte(rewrite.Replace))
+	}
+	if !bytes.Equal(rewritten, message) {
+		Log("r%d: rewrote log message", rev.Number)
+		rev.Properties.Set(svn.LogProperty, rewritten)
+	}
+}
+
+func applyFilter(rev *svn.Revision, status *Status) {
 	// We're not going to bother applying filters to metadata at this point.
 	filtered := make(map[int]bool)
-	for _, filter := range filters {
-		for _, nodeIdx := range rev.GetNodeIndexesWithPrefix(filter) {
+	for _, filter := range inRange(status.rules.Filter, rev) {
+		for _, nodeIdx := range rev.GetNodeIndexesWithPrefix(filter.Path) {
 			filtered[nodeIdx] = true
 		}
+	}
 
-		// Check any filtered history nodes.
-		for _, node := range rev.Nodes {
-			_, branchedPath, branched := node.Branched()
-			if branched && svn.MatchPathPrefix(branchedPath, filter) {
-				panic(fmt.Errorf("filter:%s would break history of %s %s %s at r%d", filter, *node.Action, *node.Kind, node.Path(), rev.Number))
+	// Check any filtered history nodes, against the filters that applied to
+	// the revision they were copied from.
+	for _, node := range rev.Nodes {
+		branchedRev, branchedPath, branched := node.Branched()
+		if !branched || branchedRev < 0 || branchedRev >= len(status.Revisions) {
+			continue
+		}
+		for _, filter := range inRange(status.rules.Filter, status.Revisions[branchedRev]) {
+			if svn.MatchPathPrefix(branchedPath, filter.Path) {
+				panic(fmt.Errorf("filter:%s would break history of %s %s %s at r%d", filter.Path, *node.Action, *node.Kind, node.Path(), rev.Number))
 			}
 		}
 	}
@@ -149,7 +301,12 @@ func applyFilter(rev *svn.Revision, filters []string) {
 			if !filtered[i] {
 				nodes = append(nodes, node)
 			} else {
-				Info("r%d: filtering node %s %s %s", rev.Number, *node.Action, *node.Kind, node.Path())
+				// Deletions have no kind.
+				kind := "node"
+				if node.Kind != nil {
+					kind = *node.Kind
+				}
+				Info("r%d: filtering node %s %s %s", rev.Number, *node.Action, kind, node.Path())
 			}
 		}
 		rev.Nodes = nodes
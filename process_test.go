@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+func TestApplyReplaceCollisions(t *testing.T) {
+	const overlap = "replace:\n  - regex: '^(a|b)/x$'\n    to: 'c/x'\n"
+	for _, test := range []struct {
+		name  string
+		rules string
+		nodes []string // "add path", "del path" or "copy path from@rev".
+		want  []string // Paths and copy sources afterwards.
+		panic string
+	}{
+		{"distinct", overlap, []string{"add a/x", "add a/y"}, []string{"c/x", "a/y"}, ""},
+		{"paths", overlap, []string{"add a/x", "add b/x"}, nil,
+			"replace maps both a/x and b/x to c/x at r2"},
+		{"same path", overlap, []string{"del a/x", "add a/x"}, []string{"c/x", "c/x"}, ""},
+		{"copy sources", overlap, []string{"copy d a/x@1", "copy e b/x@1"}, nil,
+			"replace maps both a/x@1 and b/x@1 to c/x at r2"},
+		{"copy sources at different revisions", overlap, []string{"copy d a/x@0", "copy e b/x@1"},
+			[]string{"d c/x@0", "e c/x@1"}, ""},
+		{"same copy source", overlap, []string{"copy d a/x@1", "copy e a/x@1"},
+			[]string{"d c/x@1", "e c/x@1"}, ""},
+		{"path and copy source", overlap, []string{"add a/x", "copy d b/x@1"},
+			[]string{"c/x", "d c/x@1"}, ""},
+		{"paths out of scope", overlap + "    scope: [copy-sources]\n", []string{"add a/x", "add b/x"},
+			[]string{"a/x", "b/x"}, ""},
+		{"copy sources out of scope", overlap + "    scope: [paths]\n", []string{"copy d a/x@1", "copy e b/x@1"},
+			[]string{"d a/x@1", "e b/x@1"}, ""},
+		{"paths out of range", overlap + "    to-rev: 1\n", []string{"add a/x", "add b/x"},
+			[]string{"a/x", "b/x"}, ""},
+		{"copy sources in range", overlap + "    to-rev: 1\n", []string{"copy d a/x@1", "copy e b/x@1"}, nil,
+			"replace maps both a/x@1 and b/x@1 to c/x at r2"},
+		{"copy sources out of range", overlap + "    from-rev: 2\n", []string{"copy d a/x@1", "copy e b/x@1"},
+			[]string{"d a/x@1", "e b/x@1"}, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rulesFile := filepath.Join(t.TempDir(), "rules.yml")
+			if err := os.WriteFile(rulesFile, []byte(test.rules), 0600); err != nil {
+				t.Fatal(err)
+			}
+			status, err := NewStatus()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.rules, err = NewRules(rulesFile); err != nil {
+				t.Fatal(err)
+			}
+
+			date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			for number := 0; number < 2; number++ {
+				status.AppendRevision(svn.CreateRevision(number, "", date, ""))
+			}
+			rev := svn.CreateRevision(2, "author", date, "")
+			for _, node := range test.nodes {
+				fields := strings.Fields(node)
+				switch fields[0] {
+				case "add":
+					rev.AddFileNode(fields[1], nil)
+				case "del":
+					rev.AddDeleteNode(fields[1])
+				case "copy":
+					fromPath, fromRev, _ := strings.Cut(fields[2], "@")
+					number, err := strconv.Atoi(fromRev)
+					if err != nil {
+						t.Fatal(err)
+					}
+					rev.AddCopyNode(fields[1], svn.NodeKindFile, fromPath, number)
+				}
+			}
+
+			var recovered any
+			func() {
+				defer func() { recovered = recover() }()
+				applyReplace(rev, status)
+			}()
+			if test.panic != "" {
+				if recovered == nil || fmt.Sprint(recovered) != test.panic {
+					t.Fatalf("got panic %v, expected %q", recovered, test.panic)
+				}
+				return
+			}
+			if recovered != nil {
+				t.Fatalf("unexpected panic: %v", recovered)
+			}
+
+			var got []string
+			for _, node := range rev.Nodes {
+				if fromRev, fromPath, ok := node.Branched(); ok {
+					got = append(got, fmt.Sprintf("%s %s@%d", node.Path(), fromPath, fromRev))
+				} else {
+					got = append(got, node.Path())
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, expected %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	svn "github.com/kfsone/svn-go/lib"
+	yml "gopkg.in/yaml.v3"
+)
+
+// Layouts accepted for from-date and to-date.
+var rangeDateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// RevisionRange limits a rule to the revisions between FromRev and ToRev,
+// and committed between FromDate and ToDate, inclusive. Unset bounds don't
+// limit it. A to-date without a time includes the whole day.
+type RevisionRange struct {
+	FromRev  int       `yaml:"from-rev,omitempty"`
+	ToRev    int       `yaml:"to-rev,omitempty"`
+	FromDate string    `yaml:"from-date,omitempty"`
+	ToDate   string    `yaml:"to-date,omitempty"`
+	from, to time.Time // Parsed dates; to is exclusive.
+}
+
+// Includes returns true if a revision with the given number and svn:date is
+// inside the range. Revisions without a date are outside any date bounds.
+func (r RevisionRange) Includes(number int, date time.Time) bool {
+	if number < r.FromRev || (r.ToRev != 0 && number > r.ToRev) {
+		return false
+	}
+	if (!r.from.IsZero() || !r.to.IsZero()) && date.IsZero() {
+		return false
+	}
+	if !r.from.IsZero() && date.Before(r.from) {
+		return false
+	}
+	return r.to.IsZero() || date.Before(r.to)
+}
+
+// compile checks the range and parses its dates.
+func (r *RevisionRange) compile() (err error) {
+	if r.FromRev < 0 || r.ToRev < 0 || (r.ToRev != 0 && r.ToRev < r.FromRev) {
+		return fmt.Errorf("from-rev %d to-rev %d is not an ascending range", r.FromRev, r.ToRev)
+	}
+	if r.FromDate != "" {
+		if r.from, _, err = parseRangeDate(r.FromDate); err != nil {
+			return fmt.Errorf("from-date: %w", err)
+		}
+	}
+	if r.ToDate != "" {
+		var dateOnly bool
+		if r.to, dateOnly, err = parseRangeDate(r.ToDate); err != nil {
+			return fmt.Errorf("to-date: %w", err)
+		}
+		if dateOnly {
+			r.to = r.to.AddDate(0, 0, 1)
+		} else {
+			r.to = r.to.Add(time.Nanosecond)
+		}
+	}
+	if !r.from.IsZero() && !r.to.IsZero() && !r.from.Before(r.to) {
+		return fmt.Errorf("from-date %s is after to-date %s", r.FromDate, r.ToDate)
+	}
+	return nil
+}
+
+// parseRangeDate parses a date bound, as UTC unless it gives a zone, and
+// reports whether it was only a date.
+func parseRangeDate(text string) (time.Time, bool, error) {
+	for _, layout := range rangeDateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, len(layout) == len("2006-01-02"), nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%q isn't a date such as 2006-01-02 or 2006-01-02T15:04:05Z", text)
+}
+
+// revisionDate returns the revision's svn:date, or the zero time.
+func revisionDate(rev *svn.Revision) time.Time {
+	if value, ok := rev.Properties.Get(svn.DateProperty); ok {
+		if date, err := time.Parse(svn.DateLayout, string(value)); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// inRange returns the rules whose revision ranges include rev.
+func inRange[R interface{ Includes(int, time.Time) bool }](rules []R, rev *svn.Revision) []R {
+	date := revisionDate(rev)
+	active := make([]R, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Includes(rev.Number, date) {
+			active = append(active, rule)
+		}
+	}
+	return active
+}
+
+// PathRule names a path that a rule such as 'filter' or 'retrofit-paths'
+// applies to, optionally within a revision range. It can be written as just
+// the path.
+type PathRule struct {
+	Path          string `yaml:"path"`
+	RevisionRange `yaml:",inline"`
+}
+
+// UnmarshalYAML accepts a plain path as well as a path with a range.
+func (p *PathRule) UnmarshalYAML(value *yml.Node) error {
+	if value.Kind == yml.ScalarNode {
+		return value.Decode(&p.Path)
+	}
+	type plain PathRule
+	return value.Decode((*plain)(p))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// repairDumps writes a copy of each dump file with Windows line-ending
+// translation reversed, to -outfile when there is a single dump or into
+// -outdir under the same name.
+func repairDumps(filenames []string) error {
+	if *outFilename != "" && len(filenames) > 1 {
+		return fmt.Errorf("-outfile can only take one repaired dump, use -outdir for %d", len(filenames))
+	}
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0700); err != nil {
+			return err
+		}
+	}
+
+	for _, filename := range filenames {
+		outname := *outFilename
+		if outname == "" {
+			outname = filepath.Join(*outDir, filepath.Base(filename))
+		}
+		if err := repairDump(filename, outname); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	Info("Finished")
+
+	return nil
+}
+
+// repairDump reads an entire dump, decompressing it if need be, and writes
+// the repaired copy to outname.
+func repairDump(filename, outname string) error {
+	Info("Repairing %s -> %s", filename, outname)
+
+	var in io.Reader = os.Stdin
+	if filename != stdStream {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			must(file.Close())
+		}()
+		in = file
+	}
+
+	decompressed, _, err := svn.Decompress(in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		must(decompressed.Close())
+	}()
+
+	data, err := io.ReadAll(decompressed)
+	if err != nil {
+		return err
+	}
+
+	var report *svn.CRLFRepair
+	err = writeOutput(outname, func(writer io.Writer) (err error) {
+		report, err = svn.RepairCRLF(data, writer)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	Info("%s: %d revisions, %d nodes", filename, report.Revisions, report.Nodes)
+	for _, ambiguous := range report.Ambiguous {
+		fmt.Fprintf(console, "** %s: unverified, assumed translated: %s\n", filename, ambiguous)
+	}
+
+	return nil
+}
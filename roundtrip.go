@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	svn "github.com/kfsone/svn-go/lib"
+)
+
+// maxReportedLine limits how much of a diverging line is kept for reporting.
+const maxReportedLine = 160
+
+// roundTripComparer is an io.Writer that compares everything written to it
+// with an original stream, noting where they first differ.
+type roundTripComparer struct {
+	original *bufio.Reader
+	offset   int    // Bytes that matched before any divergence.
+	line     []byte // The matching part of the line being compared.
+	diverged bool
+	expected []byte // Rest of the diverging line in the original.
+	actual   []byte // Rest of the diverging line in the output.
+	scratch  []byte
+}
+
+func newRoundTripComparer(original io.Reader) *roundTripComparer {
+	return &roundTripComparer{original: bufio.NewReader(original)}
+}
+
+func (c *roundTripComparer) Write(data []byte) (int, error) {
+	if c.diverged {
+		c.actual = appendLine(c.actual, data)
+		return len(data), nil
+	}
+
+	if cap(c.scratch) < len(data) {
+		c.scratch = make([]byte, len(data))
+	}
+	original := c.scratch[:len(data)]
+	read, _ := io.ReadFull(c.original, original)
+	original = original[:read]
+
+	same := 0
+	for same < read && original[same] == data[same] {
+		same++
+	}
+	c.track(data[:same])
+	if same == len(data) {
+		return len(data), nil
+	}
+
+	c.diverge(original[same:])
+	c.actual = appendLine(c.actual, data[same:])
+
+	return len(data), nil
+}
+
+// Finish checks that the original doesn't continue past the output.
+func (c *roundTripComparer) Finish() {
+	if !c.diverged {
+		if _, err := c.original.Peek(1); err == nil {
+			c.diverge(nil)
+		}
+	}
+}
+
+// track advances past matching data, remembering the current line.
+func (c *roundTripComparer) track(data []byte) {
+	c.offset += len(data)
+	if eol := bytes.LastIndexByte(data, '\n'); eol != -1 {
+		c.line = append(c.line[:0], data[eol+1:]...)
+	} else {
+		c.line = append(c.line, data...)
+	}
+	if len(c.line) > maxReportedLine {
+		c.line = append(c.line[:0], c.line[len(c.line)-maxReportedLine:]...)
+	}
+}
+
+// diverge records the rest of the original's line from where output differs.
+func (c *roundTripComparer) diverge(original []byte) {
+	c.diverged = true
+	c.expected = appendLine(c.expected, original)
+	if bytes.IndexByte(original, '\n') == -1 {
+		rest, _ := c.original.ReadSlice('\n')
+		c.expected = appendLine(c.expected, rest)
+	}
+}
+
+// appendLine appends data up to its first newline, within maxReportedLine.
+func appendLine(line, data []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		return line
+	}
+	if eol := bytes.IndexByte(data, '\n'); eol != -1 {
+		data = data[:eol+1]
+	}
+	if room := maxReportedLine - len(line); len(data) > room {
+		data = data[:room]
+	}
+	return append(line, data...)
+}
+
+// verifyRoundTrip re-encodes each loaded dump file, without applying any rules,
+// and compares the result with the original file byte for byte. Format 3 dumps
+// can't be compared: their deltas are expanded when they're loaded, and there is
+// no reproducing the deltas svnadmin chose.
+func verifyRoundTrip(status *Status) error {
+	for _, dumpfile := range status.DumpFiles {
+		if dumpfile.DumpFormat >= 3 {
+			return fmt.Errorf("%s: -verify-roundtrip can't compare format %d (delta) dumps; dump the repository without --deltas", dumpfile.Filename, dumpfile.DumpFormat)
+		}
+	}
+
+	failures := 0
+	for _, dumpfile := range status.DumpFiles {
+		Info("Verifying round trip: %s", dumpfile.Filename)
+
+		comparer, err := roundTripFile(status, dumpfile)
+		if err != nil {
+			return err
+		}
+		if !comparer.diverged {
+			Info("%s: identical (%d bytes)", dumpfile.Filename, comparer.offset)
+			continue
+		}
+
+		failures++
+		location := dumpfile.Locate(comparer.offset)
+		where := location.String()
+		if location.Section == svn.SectionHeaders {
+			line := string(comparer.line) + string(comparer.expected)
+			if key, _, ok := strings.Cut(line, ": "); ok {
+				where += " header " + key
+			}
+		}
+		fmt.Fprintf(console, "** %s: re-encoding diverges at %s\n", dumpfile.Filename, where)
+		fmt.Fprintf(console, "**   expected: %q\n", string(comparer.line)+string(comparer.expected))
+		fmt.Fprintf(console, "**   actual:   %q\n", string(comparer.line)+string(comparer.actual))
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d dump file(s) did not round-trip", failures, len(status.DumpFiles))
+	}
+
+	return nil
+}
+
+// roundTripFile encodes the revisions loaded from dumpfile and compares them
+// with a fresh read of the original file.
+func roundTripFile(status *Status, dumpfile *svn.DumpFile) (*roundTripComparer, error) {
+	file, err := os.Open(dumpfile.Filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		must(file.Close())
+	}()
+
+	original, _, err := svn.Decompress(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dumpfile.Filename, err)
+	}
+	defer func() {
+		must(original.Close())
+	}()
+
+	comparer := newRoundTripComparer(original)
+	enc := svn.NewEncoder(comparer)
+	first, last := dumpfile.Revisions[0].Number, dumpfile.Revisions[len(dumpfile.Revisions)-1].Number
+	var encodeErr error
+	for progress := range status.Encode(enc, first, last) {
+		if progress.Err != nil {
+			encodeErr = progress.Err
+		}
+	}
+	enc.Close()
+	if encodeErr != nil {
+		return nil, fmt.Errorf("%s: %w", dumpfile.Filename, encodeErr)
+	}
+	comparer.Finish()
+
+	return comparer, nil
+}
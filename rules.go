@@ -2,9 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"regexp"
+	"strings"
 
+	svn "github.com/kfsone/svn-go/lib"
 	yml "gopkg.in/yaml.v3"
 )
 
@@ -35,22 +38,197 @@ type Convention struct {
 }
 
 type StripProp struct {
-	Files      string `yaml:"files"`
-	fileRegexp *regexp.Regexp
-	Props      []string `yaml:"props"`
+	Files         string `yaml:"files"`
+	fileRegexp    *regexp.Regexp
+	Props         []string `yaml:"props"`
+	RevisionRange `yaml:",inline"`
+}
+
+// Targets a replace rule can apply to.
+const (
+	ReplacePaths       = "paths"        // Node paths.
+	ReplaceCopySources = "copy-sources" // Node-copyfrom-path.
+	ReplaceProperties  = "properties"   // Node properties, and revision properties other than svn:log.
+	ReplaceLog         = "log"          // svn:log.
+)
+
+var replaceTargets = []string{ReplacePaths, ReplaceCopySources, ReplaceProperties, ReplaceLog}
+
+// ReplaceRule replaces From with To in the targets listed in Scope, or in all
+// of them if there's no Scope. In paths and copy sources From only matches
+// whole leading path components; in properties and log messages it matches
+// anywhere.
+//
+// Instead of From, a rule can match paths with a Regex or a Glob, and To can
+// use what they capture as $1 or ${name}. In a glob, * and ? match within a
+// path component, ** matches any number of components, and each is captured.
+// These rules rewrite paths, copy sources and the paths in svn:mergeinfo, but
+// not the log.
+type ReplaceRule struct {
+	From          string   `yaml:"from,omitempty"`
+	Regex         string   `yaml:"regex,omitempty"`
+	Glob          string   `yaml:"glob,omitempty"`
+	To            string   `yaml:"to"`
+	Scope         []string `yaml:"scope,omitempty"`
+	pattern       *regexp.Regexp
+	RevisionRange `yaml:",inline"`
+}
+
+// Applies returns true if the rule applies to target.
+func (r *ReplaceRule) Applies(target string) bool {
+	if r.pattern != nil && target == ReplaceLog {
+		return false
+	}
+	return len(r.Scope) == 0 || svn.Index(r.Scope, target) != -1
+}
+
+// Name returns the rule's from, regex or glob, for messages.
+func (r *ReplaceRule) Name() string {
+	switch {
+	case r.Regex != "":
+		return "regex " + r.Regex
+	case r.Glob != "":
+		return "glob " + r.Glob
+	}
+	return r.From
+}
+
+// RewritePath applies the rule to a path, without leading or trailing slashes.
+func (r *ReplaceRule) RewritePath(path string) string {
+	if r.pattern == nil {
+		return svn.ReplacePathPrefix(path, r.From, r.To)
+	}
+	trimmed := strings.Trim(path, "/")
+	if !r.pattern.MatchString(trimmed) {
+		return path
+	}
+	// Captures that matched nothing can leave doubled or stray slashes.
+	parts := strings.Split(r.pattern.ReplaceAllString(trimmed, r.To), "/")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// compile checks the rule and prepares its regex or glob.
+func (r *ReplaceRule) compile() (err error) {
+	given := 0
+	for _, match := range []string{r.From, r.Regex, r.Glob} {
+		if match != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		return fmt.Errorf("replace rule for %q needs exactly one of 'from', 'regex' or 'glob'", r.Name())
+	}
+	for _, target := range r.Scope {
+		if svn.Index(replaceTargets, target) == -1 {
+			return fmt.Errorf("replace rule for %q has unknown scope %q", r.Name(), target)
+		}
+		if target == ReplaceLog && r.From == "" {
+			return fmt.Errorf("replace rule for %q rewrites paths, so can't apply to the log; use log-rewrite", r.Name())
+		}
+	}
+
+	if err := r.RevisionRange.compile(); err != nil {
+		return fmt.Errorf("replace rule for %q: %w", r.Name(), err)
+	}
+
+	switch {
+	case r.Regex != "":
+		r.pattern, err = regexp.Compile(r.Regex)
+	case r.Glob != "":
+		r.pattern, err = regexp.Compile(globPattern(r.Glob))
+	}
+	if err != nil {
+		return fmt.Errorf("replace rule for %q: %w", r.Name(), err)
+	}
+	return nil
+}
+
+// globPattern converts a path glob into an anchored regular expression that
+// captures each wildcard. A leading "**/" or trailing "/**" may match nothing,
+// so "a/**" also matches "a" itself.
+func globPattern(glob string) string {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			pattern.WriteString("(?:(.*)/)?")
+			i += 3
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			pattern.WriteString("(?:/(.*))?")
+			i += 3
+		case strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString("(.*)")
+			i += 2
+		case glob[i] == '*':
+			pattern.WriteString("([^/]*)")
+			i++
+		case glob[i] == '?':
+			pattern.WriteString("([^/])")
+			i++
+		default:
+			pattern.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			i++
+		}
+	}
+	pattern.WriteString("$")
+	return pattern.String()
+}
+
+// Replacements are replace rules, applied in the order they are declared.
+type Replacements []ReplaceRule
+
+// UnmarshalYAML accepts either a list of rules or, as earlier rule files
+// have, a map of from: to pairs that apply to everything.
+func (r *Replacements) UnmarshalYAML(value *yml.Node) error {
+	if value.Kind != yml.MappingNode {
+		return value.Decode((*[]ReplaceRule)(r))
+	}
+	// Take the pairs in the order they are written rather than as a Go map.
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		var rule ReplaceRule
+		if err := value.Content[i].Decode(&rule.From); err != nil {
+			return err
+		}
+		if err := value.Content[i+1].Decode(&rule.To); err != nil {
+			return err
+		}
+		*r = append(*r, rule)
+	}
+	return nil
+}
+
+// LogRewrite is a regular expression substitution applied to svn:log
+// messages. Replace may refer to capture groups as $1 or ${name}.
+type LogRewrite struct {
+	Match         string `yaml:"match"`
+	matchRegexp   *regexp.Regexp
+	Replace       string `yaml:"replace"`
+	RevisionRange `yaml:",inline"`
 }
 
 // Rules captures the yaml description of a ruleset.
 type Rules struct {
-	Convention Convention `yaml:"convention,omitempty"`
-	CreateAt   int        `yaml:"creation-revision,omitempty"`
+	Authors    map[string]string `yaml:"authors,omitempty"`
+	Convention Convention        `yaml:"convention,omitempty"`
+	CreateAt   int               `yaml:"creation-revision,omitempty"`
 	Filename   string
-	Filter     []string          `yaml:"filter,omitempty"`
-	OverForks  []OverFork        `yaml:"overfork,omitempty"`
-	Replace    map[string]string `yaml:"replace,omitempty"`
-	RetroPaths []string          `yaml:"retrofit-paths,omitempty"`
-	RetroProps []string          `yaml:"retrofit-props,omitempty"`
-	StripProps []StripProp       `yaml:"strip-props,omitempty"`
+	Filter     []PathRule   `yaml:"filter,omitempty"`
+	LogRewrite []LogRewrite `yaml:"log-rewrite,omitempty"`
+	OverForks  []OverFork   `yaml:"overfork,omitempty"`
+	Replace    Replacements `yaml:"replace,omitempty"`
+	RetroPaths []PathRule   `yaml:"retrofit-paths,omitempty"`
+	RetroProps []string     `yaml:"retrofit-props,omitempty"`
+	StripProps []StripProp  `yaml:"strip-props,omitempty"`
+
+	// The names the 'authors' rules map to, which need no mapping themselves.
+	mappedAuthors map[string]bool
 }
 
 // NewRules returns a new Rules object populated from the yaml
@@ -82,6 +260,44 @@ func NewRules(filename string) (rules *Rules, err error) {
 			return nil, errors.New("strip-props rule has no 'files' pattern")
 		}
 		rules.StripProps[i].fileRegexp = regexp.MustCompile(pattern)
+		if err := rules.StripProps[i].RevisionRange.compile(); err != nil {
+			return nil, fmt.Errorf("strip-props rule for %q: %w", pattern, err)
+		}
+	}
+
+	for _, list := range []struct {
+		name  string
+		rules []PathRule
+	}{{"filter", rules.Filter}, {"retrofit-paths", rules.RetroPaths}} {
+		for i := range list.rules {
+			if err := list.rules[i].RevisionRange.compile(); err != nil {
+				return nil, fmt.Errorf("%s rule for %q: %w", list.name, list.rules[i].Path, err)
+			}
+		}
+	}
+
+	for i := range rules.Replace {
+		if err := rules.Replace[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range rules.LogRewrite {
+		pattern := rules.LogRewrite[i].Match
+		if len(pattern) == 0 {
+			return nil, errors.New("log-rewrite rule has no 'match' pattern")
+		}
+		if rules.LogRewrite[i].matchRegexp, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("log-rewrite rule: %w", err)
+		}
+		if err := rules.LogRewrite[i].RevisionRange.compile(); err != nil {
+			return nil, fmt.Errorf("log-rewrite rule for %q: %w", pattern, err)
+		}
+	}
+
+	rules.mappedAuthors = make(map[string]bool, len(rules.Authors))
+	for _, author := range rules.Authors {
+		rules.mappedAuthors[author] = true
 	}
 
 	rules.Filename = filename